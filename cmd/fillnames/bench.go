@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"pg-bulk-flow/internal/benchstat"
+	"pg-bulk-flow/internal/config"
+	"pg-bulk-flow/internal/logger"
+)
+
+// benchCell одна ячейка матрицы: метод, размер пакета и режим pipeline.
+type benchCell struct {
+	Method    string `json:"method"`
+	BatchSize int    `json:"batch_size,omitempty"`
+	Pipeline  bool   `json:"pipeline"`
+}
+
+type benchResult struct {
+	benchCell
+	Inserted   int64             `json:"inserted"`
+	Elapsed    []float64         `json:"elapsed_runs"` // миллисекунды
+	ElapsedMS  benchstat.Summary `json:"elapsed_ms"`
+	RowsPerSec benchstat.Summary `json:"rows_per_sec"`
+	WALPerRow  benchstat.Summary `json:"wal_bytes_per_row"` // пусто с -server-stats=false
+}
+
+type benchReport struct {
+	Warmup  int           `json:"warmup"`
+	Repeat  int           `json:"repeat"`
+	Args    []string      `json:"args,omitempty"`
+	Results []benchResult `json:"results"`
+}
+
+// runBench реализует подкоманду bench: прогоняет матрицу методов, размеров пакета и
+// режимов pipeline, запуская fillnames отдельным процессом на каждый прогон. Аргументы
+// после флагов bench передаются каждому запуску как есть (например, -i или -type).
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s bench [flags] [-- fillnames flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	var (
+		methods   = fs.String("methods", "copyfrom,pgxbatch,unnestbatch", "Comma separated insert methods")
+		batches   = fs.String("batches", "1000,10000", "Comma separated batch sizes (for batch methods)")
+		pipelines = fs.String("pipelines", "false,true", "Comma separated pipeline modes")
+		warmup    = fs.Int("warmup", 1, "Number of warm-up runs per cell (not measured)")
+		repeat    = fs.Int("repeat", 5, "Number of measured runs per cell")
+		jsonOut   = fs.String("json", "-", "Write JSON results to `file` ('-' for stdout)")
+		mdOut     = fs.String("markdown", "", "Write markdown table to `file` (default stderr)")
+	)
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't load config: %v\n", err)
+		return 1
+	}
+	logger.SetupDefault(cfg.Log)
+
+	cells, err := benchMatrix(*methods, *batches, *pipelines)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		return 1
+	}
+	if *warmup < 0 || *repeat <= 0 {
+		fmt.Fprintln(os.Stderr, "warmup must be non-negative and repeat positive")
+		fs.Usage()
+		return 1
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		slog.Error("can't find executable", "error", err)
+		return 1
+	}
+
+	report := benchReport{
+		Warmup: *warmup,
+		Repeat: *repeat,
+		Args:   fs.Args(),
+	}
+
+	for _, cell := range cells {
+		res := benchResult{benchCell: cell}
+		rowsPerSec := make([]float64, 0, *repeat)
+		var walPerRow []float64
+
+		for i := range *warmup + *repeat {
+			log := slog.With("method", cell.Method, "batch", cell.BatchSize, "pipeline", cell.Pipeline)
+			if i < *warmup {
+				log.Info("bench warm-up", "run", i+1)
+			} else {
+				log.Info("bench run", "run", i+1-*warmup)
+			}
+
+			inserted, elapsed, wal, err := benchRun(exe, cell, fs.Args())
+			if err != nil {
+				log.Error("bench run failed", "error", err)
+				return 1
+			}
+			if i < *warmup {
+				continue
+			}
+
+			ms := float64(elapsed) / float64(time.Millisecond)
+			res.Inserted = inserted
+			res.Elapsed = append(res.Elapsed, ms)
+			rowsPerSec = append(rowsPerSec, float64(inserted)/max(elapsed.Seconds(), 1e-3))
+			if wal > 0 {
+				walPerRow = append(walPerRow, wal)
+			}
+		}
+
+		res.ElapsedMS = benchstat.Summarize(res.Elapsed)
+		res.RowsPerSec = benchstat.Summarize(rowsPerSec)
+		res.WALPerRow = benchstat.Summarize(walPerRow)
+		report.Results = append(report.Results, res)
+	}
+
+	if err := writeBenchJSON(*jsonOut, report); err != nil {
+		slog.Error("write json results failed", "error", err)
+		return 1
+	}
+	if err := writeBenchMarkdown(*mdOut, report); err != nil {
+		slog.Error("write markdown results failed", "error", err)
+		return 1
+	}
+
+	return 0
+}
+
+func benchMatrix(methods, batches, pipelines string) ([]benchCell, error) {
+	var batchSizes []int
+	for s := range strings.SplitSeq(batches, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid batch size %q", s)
+		}
+		batchSizes = append(batchSizes, n)
+	}
+
+	var modes []bool
+	for s := range strings.SplitSeq(pipelines, ",") {
+		v, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipeline mode %q", s)
+		}
+		modes = append(modes, v)
+	}
+
+	var cells []benchCell
+	for m := range strings.SplitSeq(methods, ",") {
+		m = strings.TrimSpace(m)
+		if !supportedMethods[m] {
+			return nil, fmt.Errorf("invalid method: %s", m)
+		}
+		for _, pipeline := range modes {
+			if !usesBatch(m) {
+				cells = append(cells, benchCell{Method: m, Pipeline: pipeline})
+				continue
+			}
+			for _, n := range batchSizes {
+				cells = append(cells, benchCell{Method: m, BatchSize: n, Pipeline: pipeline})
+			}
+		}
+	}
+
+	return cells, nil
+}
+
+// benchRun запускает один прогон с очисткой таблицы и возвращает из отчета вставленное
+// количество записей, время вставки и байты WAL на запись (0, если их нет в отчете).
+func benchRun(exe string, cell benchCell, extra []string) (int64, time.Duration, float64, error) {
+	args := append([]string{}, extra...)
+	args = append(args,
+		"-method", cell.Method,
+		"-pipeline="+strconv.FormatBool(cell.Pipeline),
+		"-truncate",
+	)
+	if cell.BatchSize > 0 {
+		args = append(args, "-batch", strconv.Itoa(cell.BatchSize))
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	var results struct {
+		Stats struct {
+			Elapsed  int64 `json:"elapsed"` // миллисекунды
+			Inserted int64 `json:"inserted"`
+			Server   struct {
+				WALPerRow float64 `json:"wal_bytes_per_row"`
+			} `json:"server"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		return 0, 0, 0, fmt.Errorf("decode results: %w", err)
+	}
+
+	st := results.Stats
+	return st.Inserted, time.Duration(st.Elapsed) * time.Millisecond, st.Server.WALPerRow, nil
+}
+
+func writeBenchJSON(name string, report benchReport) error {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	return writeOutput(name, os.Stdout, &out)
+}
+
+func writeBenchMarkdown(name string, report benchReport) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "warm-up: %d, repeat: %d\n\n", report.Warmup, report.Repeat)
+	fmt.Fprintln(&out, "| method | batch | pipeline | inserted | elapsed min, ms | median | p95 | stddev | rows/s min | median | p95 | stddev | WAL B/row median |")
+	fmt.Fprintln(&out, "|--------|------:|:--------:|---------:|----------------:|-------:|----:|-------:|-----------:|-------:|----:|-------:|-----------------:|")
+	for _, r := range report.Results {
+		batch := "-"
+		if r.BatchSize > 0 {
+			batch = strconv.Itoa(r.BatchSize)
+		}
+		e, rs := r.ElapsedMS, r.RowsPerSec
+		fmt.Fprintf(&out, "| %s | %s | %v | %d | %.0f | %.0f | %.0f | %.1f | %.0f | %.0f | %.0f | %.0f | %.1f |\n",
+			r.Method, batch, r.Pipeline, r.Inserted,
+			e.Min, e.Median, e.P95, e.StdDev,
+			rs.Min, rs.Median, rs.P95, rs.StdDev,
+			r.WALPerRow.Median)
+	}
+	return writeOutput(name, os.Stderr, &out)
+}
+
+// writeOutput пишет data в файл name, в def если name пуст, или в stdout для "-".
+func writeOutput(name string, def io.Writer, data *bytes.Buffer) error {
+	switch name {
+	case "":
+		_, err := data.WriteTo(def)
+		return err
+	case "-":
+		_, err := data.WriteTo(os.Stdout)
+		return err
+	}
+	return os.WriteFile(name, data.Bytes(), 0o644)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+
+	"pg-bulk-flow/internal/parser"
+	"pg-bulk-flow/internal/scanner"
+)
+
+var (
+	format    = flag.String("format", "jsonl", "Input format: jsonl, csv or tsv")
+	columns   = flag.String("columns", "", "Column mapping for csv/tsv, e.g. text=1,count=3,gender=2 or text=surname (default text=1,count=2,gender=3, or by header names with -header)")
+	header    = flag.Bool("header", false, "First csv/tsv line is a header")
+	delimiter = flag.String("delimiter", "", "Field delimiter for csv/tsv (default ',' for csv, tab for tsv)")
+	quote     = flag.String("quote", "", "Quote character for csv/tsv (default '\"' for csv, none for tsv; 'none' disables quoting)")
+)
+
+// statsParser парсер со статистикой (parser.Parser или parser.CSVParser).
+type statsParser interface {
+	scanner.Parser
+	Stats() parser.Stats
+}
+
+// inputFormat создает n парсеров для выбранного формата и, если нужно, обработчик
+// заголовка.
+type inputFormat struct {
+	parsers []statsParser
+	header  func(line []byte) error
+	csv     *parser.CSVFormat // nil для jsonl
+}
+
+func newInputFormat(n int) (*inputFormat, error) {
+	f := &inputFormat{parsers: make([]statsParser, n)}
+
+	if *format == "jsonl" {
+		for i := range f.parsers {
+			f.parsers[i] = new(parser.Parser)
+		}
+		return f, nil
+	}
+
+	var comma, q byte
+	switch *format {
+	case "csv":
+		comma, q = ',', '"'
+	case "tsv":
+		comma, q = '\t', 0
+	default:
+		return nil, fmt.Errorf("unknown format %q", *format)
+	}
+
+	if *delimiter != "" {
+		c, err := parseChar(*delimiter)
+		if err != nil {
+			return nil, fmt.Errorf("delimiter: %w", err)
+		}
+		comma = c
+	}
+
+	if *quote == "none" {
+		q = 0
+	} else if *quote != "" {
+		c, err := parseChar(*quote)
+		if err != nil {
+			return nil, fmt.Errorf("quote: %w", err)
+		}
+		q = c
+	}
+
+	defaultColumns := "text=1,count=2,gender=3"
+	if *header {
+		defaultColumns = "text=text,count=count,gender=gender"
+	}
+
+	csvFormat, err := parser.NewCSVFormat(comma, q, cmp.Or(*columns, defaultColumns))
+	if err != nil {
+		return nil, err
+	}
+
+	f.csv = csvFormat
+	if *header {
+		f.header = csvFormat.ParseHeader
+	} else if csvFormat.NeedsHeader() {
+		return nil, fmt.Errorf("columns %q refer to header names, but -header is not set", *columns)
+	}
+
+	for i := range f.parsers {
+		f.parsers[i] = parser.NewCSVParser(csvFormat)
+	}
+	return f, nil
+}
+
+func (f *inputFormat) scannerParsers() []scanner.Parser {
+	out := make([]scanner.Parser, len(f.parsers))
+	for i, p := range f.parsers {
+		out[i] = p
+	}
+	return out
+}
+
+func (f *inputFormat) stats() parser.Stats {
+	var stats parser.Stats
+	for _, p := range f.parsers {
+		stats.Add(p.Stats())
+	}
+	return stats
+}
+
+func parseChar(s string) (byte, error) {
+	switch s {
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	if len(s) != 1 {
+		return 0, fmt.Errorf("want single ASCII character, got %q", s)
+	}
+	return s[0], nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"pg-bulk-flow/internal/synth"
+)
+
+// runGen реализует подкоманду gen: пишет n синтетических записей JSONL в формате,
+// который принимает загрузчик, в файл или stdout (например, в конвейер к fillnames -i -).
+func runGen(args []string) int {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s gen [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	def := synth.DefaultConfig
+	var (
+		n          = fs.Int64("n", 1_000_000, "Number of records")
+		out        = fs.String("o", "-", "Write records to `file` ('-' for stdout)")
+		seed       = fs.Uint64("seed", def.Seed, "Random seed; the same seed and flags give the same output")
+		minLen     = fs.Int("len-min", def.MinLen, "Minimum name length in letters (raised as needed to keep names unique)")
+		maxLen     = fs.Int("len-max", def.MaxLen, "Maximum name length in letters")
+		meanLen    = fs.Float64("len-mean", def.MeanLen, "Mean name length (normal distribution)")
+		stdDevLen  = fs.Float64("len-stddev", def.StdDevLen, "Standard deviation of name length")
+		latin      = fs.Float64("latin", def.Latin, "Share of Latin names, the rest are Cyrillic")
+		female     = fs.Float64("female", def.Female, "Share of female records")
+		unknown    = fs.Float64("unknown-gender", def.Unknown, "Share of records with unknown gender")
+		ethnic     = fs.Float64("ethnic", def.Ethnic, "Share of records with the ethnic field")
+		numberLong = fs.Float64("number-long", def.NumberLong, `Share of counts written as {"$numberLong": "..."}`)
+		malformed  = fs.Float64("malformed", def.Malformed, "Share of deliberately malformed lines the loader must reject")
+	)
+	fs.Parse(args)
+
+	cfg := synth.Config{
+		Seed:       *seed,
+		MinLen:     *minLen,
+		MaxLen:     *maxLen,
+		MeanLen:    *meanLen,
+		StdDevLen:  *stdDevLen,
+		Latin:      *latin,
+		Female:     *female,
+		Unknown:    *unknown,
+		Ethnic:     *ethnic,
+		NumberLong: *numberLong,
+		Malformed:  *malformed,
+	}
+	if *n < 0 {
+		fmt.Fprintln(os.Stderr, "number of records must be non-negative")
+		fs.Usage()
+		return 1
+	}
+	gen, err := synth.New(cfg, *n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		return 1
+	}
+
+	if *out == "-" {
+		// По умолчанию запись в закрытый stdout завершает процесс сигналом SIGPIPE;
+		// с игнорированием сигнала Write возвращает EPIPE, и выход остается чистым
+		signal.Ignore(syscall.SIGPIPE)
+		if err := writeGen(os.Stdout, gen, *n); err != nil {
+			// закрытый читатель конвейера (например, head) — не ошибка генератора
+			if errors.Is(err, syscall.EPIPE) {
+				return 0
+			}
+			fmt.Fprintf(os.Stderr, "write records failed: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create output failed: %v\n", err)
+		return 1
+	}
+	if err := writeGen(f, gen, *n); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "write records failed: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "close output failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func writeGen(w io.Writer, gen *synth.Generator, n int64) error {
+	bw := bufio.NewWriterSize(w, 256<<10)
+	var line []byte
+	for range n {
+		line = gen.AppendLine(line[:0])
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -5,24 +5,36 @@ import (
 	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 	"time"
 
+	"pg-bulk-flow/internal/checkpoint"
 	"pg-bulk-flow/internal/config"
 	"pg-bulk-flow/internal/database"
+	"pg-bulk-flow/internal/decompress"
 	"pg-bulk-flow/internal/inserter"
 	"pg-bulk-flow/internal/inserter/copyfrom"
+	"pg-bulk-flow/internal/inserter/copyraw"
+	"pg-bulk-flow/internal/inserter/parallelcopy"
+	"pg-bulk-flow/internal/inserter/pgpipeline"
 	"pg-bulk-flow/internal/inserter/pgxbatch"
 	"pg-bulk-flow/internal/inserter/unnestbatch"
+	"pg-bulk-flow/internal/inserter/valuesbatch"
 	"pg-bulk-flow/internal/logger"
 	"pg-bulk-flow/internal/model"
 	"pg-bulk-flow/internal/parser"
 	"pg-bulk-flow/internal/profiling"
+	"pg-bulk-flow/internal/progress"
 	"pg-bulk-flow/internal/scanner"
+	"pg-bulk-flow/internal/serverstats"
 	"pg-bulk-flow/internal/strutils"
 
 	"github.com/joho/godotenv"
@@ -30,24 +42,92 @@ import (
 
 const (
 	defaultBatchSize = 1000
+	defaultMaxSyncs  = 8
+	defaultMaxLine   = 1 << 20         // 1 MiB
 	defaulTimeout    = 1 * time.Minute // чтобы не ждать вечность
 )
 
 var (
-	inputFile = flag.String("i", "", "Input file ($INPUT_FILE, use '-' or empty for stdin)")
+	inputFile = flag.String("i", "", "Input file ($INPUT_FILE, use '-' or empty for stdin). May be compressed with gzip, zstd, bzip2 or xz")
 	nameType  = flag.String("type", "", "Type of names to insert ($NAME_TYPE). Available values: "+strutils.Join(model.AllNameTypes, ", "))
 	timeout   = flag.Duration("timeout", defaulTimeout, "Maximum processing duration (0 or negative means no timeout)")
-	method    = flag.String("method", "copyfrom", "Insert method to use: copyfrom, pgxbatch or unnestbatch")
-	batchSize = flag.Int("batch", defaultBatchSize, "Number of records per batch insert (has no effect when method=copyfrom)")
+	method    = flag.String("method", "copyfrom", "Insert method to use: copyfrom, copyraw, copytext, copycsv, parallelcopy, pgxbatch, pgpipeline, unnestbatch or valuesbatch")
+	batchSize = flag.Int("batch", defaultBatchSize, "Number of records per batch insert (has no effect for COPY methods)")
+	workers   = flag.Int("workers", runtime.NumCPU(), "Number of parallel COPY connections (only for method=parallelcopy)")
+	maxSyncs  = flag.Int("max-syncs", defaultMaxSyncs, "Maximum unacknowledged pipeline Sync points, one per batch (only for method=pgpipeline)")
 	truncate  = flag.Bool("truncate", false, "Clear the table before inserting new records")
 	pipeline  = flag.Bool("pipeline", false, "Enable concurrent scanning and inserting for better performance")
+	maxLine   = flag.Int("max-line", defaultMaxLine, "Maximum input line size in bytes")
+	skipLong  = flag.Bool("skip-oversize", false, "Skip lines longer than -max-line instead of failing")
+	parseN    = flag.Int("parse-workers", 1, "Number of parallel parser goroutines")
+	ordered   = flag.Bool("parse-ordered", true, "Keep input order when -parse-workers > 1")
+	script    = flag.String("script", model.DefaultNameRules.Script.String(), "Allowed name script: "+strutils.Join(model.AllScripts, ", ")+" (mixed allows both, but not within one word)")
+	maxName   = flag.Int("max-name-len", model.DefaultNameRules.MaxLength, "Maximum name length in characters")
+	capital   = flag.Bool("capitalize", model.DefaultNameRules.Capitalize, "Capitalize the first letter of each name part (may merge source spellings like иван and Иван into one key; use with -on-conflict sum)")
+	inferSex  = flag.Bool("infer-gender", false, "Detect unknown gender by surname or patronymic suffix")
+	rejects   = flag.String("rejects", "", "Write rejected input lines as JSONL to `file`")
+	conflict  = flag.String("on-conflict", "error", "Action on duplicate (name_text, name_type, gender): "+strutils.Join(inserter.AllConflictModes, ", "))
+	commitN   = flag.Int64("commit-every", 0, "Commit a transaction every N records (0 means autocommit; not for method=parallelcopy)")
+	batchErr  = flag.String("on-batch-error", "abort", "Action when the server rejects a batch: abort or bisect (only for method=pgxbatch or unnestbatch)")
+	deadFile  = flag.String("dead-letter", "", "Write rows rejected by the server in bisect mode as JSONL to `file`")
+	ckptFile  = flag.String("checkpoint", "", "Save the last committed line to `file` and skip loaded lines on rerun (requires -commit-every and -on-conflict skip or replace; the chunk committed right before a crash may be loaded again)")
+	srvStats  = flag.Bool("server-stats", true, "Report WAL bytes, pg_stat_database and pg_stat_io counters and table size growth during the load")
+	progEvery = flag.Duration("progress", 0, "Log lines read, rows inserted, rate and ETA to stderr every `interval` (0 disables)")
 )
 
+var (
+	conflictMode   inserter.ConflictMode
+	batchErrorMode inserter.BatchErrorMode
+)
+
+var supportedMethods = map[string]bool{
+	"copyfrom":     true,
+	"copyraw":      true,
+	"copytext":     true,
+	"copycsv":      true,
+	"parallelcopy": true,
+	"pgxbatch":     true,
+	"pgpipeline":   true,
+	"unnestbatch":  true,
+	"valuesbatch":  true,
+}
+
+// usesBatch сообщает, учитывает ли метод размер пакета (-batch).
+func usesBatch(method string) bool {
+	switch method {
+	case "copyfrom", "copyraw", "copytext", "copycsv", "parallelcopy":
+		return false
+	}
+	return true
+}
+
+// batchErrorString режим -on-batch-error для отчета, только для методов, которые его поддерживают.
+func batchErrorString() string {
+	if *method != "pgxbatch" && *method != "unnestbatch" {
+		return ""
+	}
+	return batchErrorMode.String()
+}
+
 func main() {
 	godotenv.Load()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "gen":
+			os.Exit(runGen(os.Args[2:]))
+		}
+	}
 	flag.Parse()
 	cfg := loadConfig()
 	logger.SetupDefault(cfg.Log)
+	if cfg.PprofEnable {
+		if err := profiling.Serve(cfg.PprofAddr); err != nil {
+			slog.Error("start pprof server failed", "error", err)
+			os.Exit(1)
+		}
+	}
 	os.Exit(run(cfg))
 }
 
@@ -57,18 +137,13 @@ func loadConfig() *config.Config {
 		log.Fatalf("can't load config: %v", err)
 	}
 
-	supportedMethods := map[string]bool{
-		"copyfrom":    true,
-		"pgxbatch":    true,
-		"unnestbatch": true,
-	}
 	if !supportedMethods[*method] {
 		fmt.Fprintf(os.Stderr, "invalid method: %s\n", *method)
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if *method == "copyfrom" {
+	if !usesBatch(*method) {
 		*batchSize = 0 // чтобы избежать появления в отчете
 	} else if *batchSize <= 0 {
 		fmt.Fprintln(os.Stderr, "batch size must be positive")
@@ -76,10 +151,141 @@ func loadConfig() *config.Config {
 		os.Exit(1)
 	}
 
+	if *method != "parallelcopy" {
+		*workers = 0 // чтобы избежать появления в отчете
+	} else if *workers <= 0 {
+		fmt.Fprintln(os.Stderr, "workers must be positive")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else {
+		cfg.DB.MaxConns = int32(*workers)
+	}
+
+	if *method != "pgpipeline" {
+		*maxSyncs = 0 // чтобы избежать появления в отчете
+	} else if *maxSyncs <= 0 {
+		fmt.Fprintln(os.Stderr, "max syncs must be positive")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if *parseN <= 0 {
+		fmt.Fprintln(os.Stderr, "parse workers must be positive")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if *maxLine <= 0 {
+		fmt.Fprintln(os.Stderr, "max line size must be positive")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if v, err := model.ParseScript(*script); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid script: %v\n", err)
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else if *maxName <= 0 {
+		fmt.Fprintln(os.Stderr, "max name length must be positive")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else {
+		model.SetNameRules(model.NameRules{MaxLength: *maxName, Script: v, Capitalize: *capital})
+	}
+
+	if *commitN < 0 {
+		fmt.Fprintln(os.Stderr, "commit-every must not be negative")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else if *commitN > 0 && *method == "parallelcopy" {
+		// у каждого воркера своя сессия, общей транзакции нет
+		fmt.Fprintln(os.Stderr, "commit-every is not supported by parallelcopy")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else if *commitN > 0 && *parseN > 1 && !*ordered {
+		// иначе последняя зафиксированная строка не говорит, какие строки загружены
+		fmt.Fprintln(os.Stderr, "commit-every requires -parse-ordered")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if v, err := inserter.ParseBatchErrorMode(*batchErr); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid on-batch-error mode: %v\n", err)
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else if v == inserter.BatchErrorBisect && *method != "pgxbatch" && *method != "unnestbatch" {
+		fmt.Fprintln(os.Stderr, "on-batch-error=bisect is supported only by pgxbatch and unnestbatch")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else if v == inserter.BatchErrorBisect && *commitN > 0 {
+		// ошибка прерывает всю явную транзакцию, повторять части пакета внутри нее нельзя
+		fmt.Fprintln(os.Stderr, "on-batch-error=bisect can't be combined with -commit-every")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else if v != inserter.BatchErrorBisect && *deadFile != "" {
+		fmt.Fprintln(os.Stderr, "dead-letter requires -on-batch-error=bisect")
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else {
+		batchErrorMode = v
+	}
+
+	if *ckptFile != "" && *commitN == 0 {
+		fmt.Fprintln(os.Stderr, "checkpoint requires -commit-every")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if v, err := inserter.ParseConflictMode(*conflict); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid on-conflict mode: %v\n", err)
+		flag.PrintDefaults()
+		os.Exit(1)
+	} else {
+		conflictMode = v
+	}
+
+	// checkpoint сохраняется после COMMIT: при падении между ними последняя часть
+	// загружается повторно, и повтор должен быть идемпотентным
+	if *ckptFile != "" && conflictMode != inserter.ConflictSkip && conflictMode != inserter.ConflictReplace {
+		fmt.Fprintln(os.Stderr, "checkpoint requires -on-conflict skip or replace")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
 	if *inputFile != "" {
 		cfg.InputFile = *inputFile
 	}
 
+	switch *source {
+	case sourceFile:
+	case sourceSynthetic:
+		if *synRows <= 0 {
+			fmt.Fprintln(os.Stderr, "synthetic rows must be positive")
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+		if err := syntheticConfig().Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid synthetic source: %v\n", err)
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+		if *ckptFile != "" {
+			fmt.Fprintln(os.Stderr, "checkpoint requires -source=file")
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "invalid source: %s\n", *source)
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if *ckptFile != "" && (cfg.InputFile == "" || cfg.InputFile == "-") {
+		fmt.Fprintln(os.Stderr, "checkpoint requires an input file, not stdin")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
 	if *nameType != "" {
 		if v, err := model.ParseNameType(*nameType); err != nil {
 			fmt.Fprintf(os.Stderr, "invalid name type: %v\n", err)
@@ -94,31 +300,98 @@ func loadConfig() *config.Config {
 }
 
 type totalStats struct {
-	Elapsed  time.Duration `json:"elapsed,omitempty"`
-	Parser   parser.Stats  `json:"parser,omitempty"`
-	Scanner  scanner.Stats `json:"scanner,omitempty"`
-	Inserted int64         `json:"inserted,omitempty"`
+	Elapsed  time.Duration            `json:"elapsed,omitempty"`
+	Input    decompress.Stats         `json:"input,omitempty"`
+	Parser   parser.Stats             `json:"parser,omitempty"`
+	Scanner  scanner.Stats            `json:"scanner,omitempty"`
+	Inserted int64                    `json:"inserted,omitempty"`
+	Workers  []int64                  `json:"workers,omitempty"`       // вставлено каждым воркером (parallelcopy)
+	Conflict *inserter.ConflictStats  `json:"conflict,omitempty"`      // только для on-conflict != error
+	Commit   *inserter.CommitProgress `json:"commit,omitempty"`        // только для commit-every > 0
+	Dead     int64                    `json:"dead_lettered,omitempty"` // отброшено сервером в режиме bisect
+	Timings  *inserter.BatchTimings   `json:"timings,omitempty"`       // только для pgxbatch и unnestbatch
+	Server   *serverstats.Delta       `json:"server,omitempty"`        // изменения на сервере за время загрузки
+	Parse    *parseCost               `json:"synthetic,omitempty"`     // только для source=synthetic
 }
 
 type insertConfig struct {
+	Source    string         `json:"source,omitempty"`
 	Input     string         `json:"input,omitempty"`
+	Format    string         `json:"format,omitempty"`
 	NameType  model.NameType `json:"name_type,omitempty"`
 	Method    string         `json:"method,omitempty"`
 	Pipeline  bool           `json:"pipeline,omitempty"`
 	BatchSize int            `json:"batch_size,omitempty"`
+	Workers   int            `json:"workers,omitempty"`
+	MaxSyncs  int            `json:"max_syncs,omitempty"`
+	Parsers   int            `json:"parse_workers,omitempty"`
+	Ordered   bool           `json:"parse_ordered,omitempty"`
+	Infer     bool           `json:"infer_gender,omitempty"`
+	Conflict  string         `json:"on_conflict,omitempty"`
+	BatchErr  string         `json:"on_batch_error,omitempty"`
+	CommitN   int64          `json:"commit_every,omitempty"`
+	Resumed   int            `json:"resumed_after_line,omitempty"`
+	SynRows   int64          `json:"synthetic_rows,omitempty"`
+	SynLenMin int            `json:"synthetic_len_min,omitempty"`
+	SynLenMax int            `json:"synthetic_len_max,omitempty"`
 	Timeout   time.Duration  `json:"timeout,omitempty"`
 }
 
 func run(cfg *config.Config) int {
-	input := os.Stdin
-	if cfg.InputFile != "" && cfg.InputFile != "-" {
-		var err error
-		input, err = os.Open(cfg.InputFile)
+	inputFormat, err := newInputFormat(*parseN)
+	if err != nil {
+		slog.Error("invalid input format", "error", err)
+		return 1
+	}
+
+	var (
+		reader    *decompress.Reader // только для -source=file
+		inputSize int64
+		state     checkpoint.State
+	)
+	if *source == sourceFile {
+		input := os.Stdin
+		if cfg.InputFile != "" && cfg.InputFile != "-" {
+			input, err = os.Open(cfg.InputFile)
+			if err != nil {
+				slog.Error("open file failed", "error", err)
+				return 1
+			}
+			defer input.Close()
+		}
+
+		// Размер известен только для обычного файла (в том числе перенаправленного в
+		// stdin), без него прогресс выводится без ETA
+		if fi, err := input.Stat(); err == nil && fi.Mode().IsRegular() {
+			inputSize = fi.Size()
+		}
+
+		reader, err = decompress.NewReader(input, cfg.InputFile)
 		if err != nil {
-			slog.Error("open file failed", "error", err)
+			slog.Error("open input failed", "error", err)
 			return 1
 		}
-		defer input.Close()
+		defer reader.Close()
+	}
+
+	if *ckptFile != "" {
+		id, err := checkpoint.Identify(cfg.InputFile)
+		if err != nil {
+			slog.Error("identify input failed", "error", err)
+			return 1
+		}
+		if state, err = checkpoint.Load(*ckptFile, id); err != nil {
+			slog.Error("load checkpoint failed", "error", err)
+			return 1
+		}
+		if state.LastLine > 0 {
+			if *truncate {
+				slog.Error("refusing to truncate a partially loaded table; remove the checkpoint to start over",
+					"checkpoint", *ckptFile)
+				return 1
+			}
+			slog.Info("resuming load", "after_line", state.LastLine, "committed", state.Committed)
+		}
 	}
 
 	conn, err := database.Connect(cfg.DB)
@@ -135,27 +408,150 @@ func run(cfg *config.Config) int {
 		}
 	}
 
-	parser := new(parser.Parser)
-	scanner := scanner.New(input, cfg.NameType, parser)
+	var rejecter scanner.Rejecter
+	if *rejects != "" {
+		f, err := os.Create(*rejects)
+		if err != nil {
+			slog.Error("create rejects file failed", "error", err)
+			return 1
+		}
+		defer f.Close()
 
-	var inserter inserter.Inserter
+		rw := scanner.NewRejectWriter(f)
+		defer func() {
+			if err := rw.Flush(); err != nil {
+				slog.Error("flush rejects file failed", "error", err)
+			}
+		}()
+		rejecter = rw
+	}
+
+	var deadLetter inserter.DeadLetterer
+	if *deadFile != "" {
+		f, err := os.Create(*deadFile)
+		if err != nil {
+			slog.Error("create dead letter file failed", "error", err)
+			return 1
+		}
+		defer f.Close()
+
+		dw := inserter.NewDeadLetterWriter(f)
+		defer func() {
+			if err := dw.Flush(); err != nil {
+				slog.Error("flush dead letter file failed", "error", err)
+			}
+		}()
+		deadLetter = dw
+	}
+
+	var (
+		src nameSource
+		sc  *scanner.Scanner // только для -source=file
+	)
+	if *source == sourceFile {
+		sc = scanner.New(reader, cfg.NameType, inputFormat.parsers[0])
+		sc.SetParseWorkers(inputFormat.scannerParsers(), *ordered)
+		sc.SetHeader(inputFormat.header)
+		sc.SetInferGender(*inferSex)
+		sc.SetRejecter(rejecter)
+		sc.SetMaxLine(*maxLine, *skipLong)
+		sc.SetSkipLines(state.LastLine)
+		src = sc
+	} else {
+		syn, err := newSyntheticSource(*synRows, cfg.NameType)
+		if err != nil {
+			slog.Error("invalid synthetic source", "error", err)
+			return 1
+		}
+		src = syn
+	}
+
+	var ins inserter.Inserter
 	switch *method {
 	case "copyfrom":
-		inserter = copyfrom.New(conn)
+		ins = copyfrom.New(conn, conflictMode)
+	case "copyraw":
+		ins = copyraw.New(conn, copyraw.FormatBinary, conflictMode)
+	case "copytext":
+		ins = copyraw.New(conn, copyraw.FormatText, conflictMode)
+	case "copycsv":
+		ins = copyraw.New(conn, copyraw.FormatCSV, conflictMode)
+	case "parallelcopy":
+		pool, err := database.Open(cfg.DB)
+		if err != nil {
+			slog.Error("database open failed", "error", err)
+			return 1
+		}
+		defer pool.Close()
+		ins = parallelcopy.New(pool, *workers, conflictMode)
 	case "pgxbatch":
-		inserter = pgxbatch.New(conn, *batchSize)
+		ins = pgxbatch.New(conn, *batchSize, conflictMode)
+	case "pgpipeline":
+		ins = pgpipeline.New(conn, *batchSize, *maxSyncs, conflictMode)
 	case "unnestbatch":
-		inserter = unnestbatch.New(conn, *batchSize)
+		ins = unnestbatch.New(conn, *batchSize, conflictMode)
+	case "valuesbatch":
+		vb := valuesbatch.New(conn, *batchSize, conflictMode)
+		if vb.BatchSize() < *batchSize {
+			slog.Warn("batch size exceeds bind parameter limit", "batch", *batchSize, "max", vb.BatchSize())
+			*batchSize = vb.BatchSize()
+		}
+		ins = vb
 	default:
 		slog.Error("unknown insert method", "method", *method)
 		return 1
 	}
 
-	insert := inserter.Insert
+	if batchErrorMode != inserter.BatchErrorAbort {
+		ins.(interface {
+			SetOnBatchError(inserter.BatchErrorMode, inserter.DeadLetterer)
+		}).SetOnBatchError(batchErrorMode, deadLetter)
+	}
+
+	// Committer не передает тайминги вложенного инсертера, поэтому он запоминается до обертки
+	timer, _ := ins.(inserter.TimingsReporter)
+
+	var committer *inserter.Committer
+	if *commitN > 0 {
+		committer = inserter.NewCommitter(conn, ins, *commitN)
+		if *ckptFile != "" {
+			committer.SetOnCommit(func(p inserter.CommitProgress) error {
+				st := state
+				st.LastLine = p.LastLine
+				st.Committed += p.Committed
+				return checkpoint.Save(*ckptFile, st)
+			})
+		}
+		ins = committer
+	}
+
+	insert := ins.Insert
 	if *pipeline {
-		insert = inserter.InsertWithPipeline
+		insert = ins.InsertWithPipeline
 	}
 
+	// Первый сигнал отменяет только чтение входа: вставка дописывает уже прочитанные
+	// записи, закрывает COPY или последний пакет штатно, и выводится частичный отчет.
+	// После него обработка сигналов возвращается к стандартной, так что повторный
+	// сигнал завершает процесс сразу.
+	// Сигнал записываем в причину отмены сами: signal.NotifyContext задает ее только
+	// начиная с Go 1.26.
+	readCtx, cancelRead := context.WithCancelCause(context.Background())
+	defer cancelRead(nil)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case sig := <-sigs:
+			signal.Stop(sigs)
+			cause := fmt.Errorf("%w: %v", errInterrupted, sig)
+			slog.Warn("interrupted, finishing current batch; repeat to force exit", "cause", cause)
+			cancelRead(cause)
+		case <-readCtx.Done():
+		}
+	}()
+
 	ctx := context.Background()
 	if *timeout >= 0 {
 		var cancel context.CancelFunc
@@ -169,40 +565,165 @@ func run(cfg *config.Config) int {
 		insErr  error
 	)
 
+	// Метрики сервера вспомогательные: если опросить его не удалось, загрузка
+	// все равно выполняется, а в отчете их просто нет
+	var before *serverstats.Snapshot
+	if *srvStats {
+		if s, err := serverstats.Take(context.Background(), conn); err != nil {
+			slog.Warn("sample server stats failed", "error", err)
+		} else {
+			before = &s
+		}
+	}
+
+	stopProgress := func() {}
+	if *progEvery > 0 {
+		var progressCtx context.Context
+		progressCtx, stopProgress = context.WithCancel(context.Background())
+		defer stopProgress()
+
+		rows, _ := ins.(inserter.ProgressReporter)
+		go progress.Run(progressCtx, *progEvery, inputSize, func() progress.Counters {
+			c := progress.Counters{Lines: src.Lines()}
+			if reader != nil {
+				c.Bytes = reader.Stats().Compressed
+			}
+			if rows != nil {
+				c.Rows = rows.Rows()
+			}
+			return c
+		})
+	}
+
 	profiling.Do(func() {
 		start := time.Now()
-		count, insErr = insert(ctx, scanner.Scan(ctx))
+		count, insErr = insert(ctx, src.Scan(readCtx))
 		elapsed = time.Since(start)
 	})
+	stopProgress()
+
+	var conflictStats *inserter.ConflictStats
+	if v, ok := ins.(inserter.ConflictReporter); ok && conflictMode != inserter.ConflictError {
+		stats := v.ConflictStats()
+		conflictStats = &stats
+	}
 
-	if err := scanner.Err(); err != nil {
+	var serverDelta *serverstats.Delta
+	if before != nil {
+		// удельные величины считаются на записанные строки: пропущенные при
+		// on-conflict=skip ничего не пишут
+		written := count
+		if conflictStats != nil {
+			written = conflictStats.Inserted + conflictStats.Updated
+		}
+		if after, err := serverstats.Take(context.Background(), conn); err != nil {
+			slog.Warn("sample server stats failed", "error", err)
+		} else {
+			d := serverstats.Diff(*before, after, written)
+			serverDelta = &d
+		}
+	}
+	stopped := interrupted(readCtx)
+
+	// С commit-every или после прерывания отчет выводится и при ошибке: он показывает,
+	// что успело загрузиться, чтобы можно было решить, продолжать ли загрузку.
+	var failure error
+	if err := src.Err(); err != nil {
 		slog.Error("scan failed", "error", err)
-		return 1
+		failure = err
 	}
 
 	if insErr != nil {
 		slog.Error("insert failed", "error", insErr)
+		failure = cmp.Or(failure, insErr)
+	}
+
+	if failure != nil && committer == nil && !stopped {
 		return 1
 	}
 
+	var cost *parseCost
+	if *source == sourceSynthetic && failure == nil {
+		// после прерывания записей меньше, чем заказано
+		c, err := estimateParseCost(context.Background(), src.Lines(), cfg.NameType, elapsed)
+		if err != nil {
+			slog.Warn("estimate parse cost failed", "error", err)
+		} else {
+			cost = &c
+		}
+	}
+
+	var deadLettered int64
+	if v, ok := ins.(inserter.DeadLetterReporter); ok {
+		deadLettered = v.DeadLettered()
+	}
+
+	var commitProgress *inserter.CommitProgress
+	if committer != nil {
+		progress := committer.Progress()
+		commitProgress = &progress
+	}
+
+	var batchTimings *inserter.BatchTimings
+	if timer != nil {
+		timings := timer.BatchTimings()
+		batchTimings = &timings
+	}
+
+	var workerCounts []int64
+	if v, ok := ins.(*parallelcopy.Inserter); ok {
+		workerCounts = v.Counts()
+	}
+
 	results := struct {
 		Config insertConfig `json:"config,omitempty"`
 		Stats  totalStats   `json:"stats,omitempty"`
+		Error  string       `json:"error,omitempty"`
+		Intr   bool         `json:"interrupted,omitempty"`
 	}{
 		Config: insertConfig{
-			Input:     cmp.Or(cfg.InputFile, "-"),
+			Source:    *source,
 			NameType:  cfg.NameType,
 			Method:    *method,
 			BatchSize: *batchSize,
+			Workers:   *workers,
+			MaxSyncs:  *maxSyncs,
+			Conflict:  conflictMode.String(),
+			BatchErr:  batchErrorString(),
+			CommitN:   *commitN,
+			Resumed:   state.LastLine,
+			Parsers:   *parseN,
+			Ordered:   *ordered && *parseN > 1,
+			Infer:     *inferSex,
 			Pipeline:  *pipeline,
 			Timeout:   *timeout / time.Millisecond, // to milliseconds
 		},
 		Stats: totalStats{
 			Elapsed:  elapsed / time.Millisecond, // to milliseconds
-			Parser:   parser.Stats(),
-			Scanner:  scanner.Stats(),
 			Inserted: count,
+			Workers:  workerCounts,
+			Conflict: conflictStats,
+			Commit:   commitProgress,
+			Dead:     deadLettered,
+			Timings:  batchTimings,
+			Server:   serverDelta,
+			Parse:    cost,
 		},
+		Intr: stopped,
+	}
+	if *source == sourceFile {
+		results.Config.Input = cmp.Or(cfg.InputFile, "-")
+		results.Config.Format = *format
+		results.Stats.Input = reader.Stats()
+		results.Stats.Parser = inputFormat.stats()
+		results.Stats.Scanner = sc.Stats()
+	} else {
+		results.Config.SynRows = *synRows
+		results.Config.SynLenMin = *synLenMin
+		results.Config.SynLenMax = *synLenMax
+	}
+	if failure != nil {
+		results.Error = failure.Error()
 	}
 
 	var out bytes.Buffer
@@ -218,5 +739,16 @@ func run(cfg *config.Config) int {
 		return 1
 	}
 
+	if failure != nil || stopped {
+		return 1
+	}
 	return 0
 }
+
+// errInterrupted причина отмены чтения по сигналу.
+var errInterrupted = errors.New("interrupted by signal")
+
+// interrupted сообщает, отменено ли чтение сигналом, а не при обычном завершении.
+func interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errInterrupted)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"iter"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"pg-bulk-flow/internal/model"
+	"pg-bulk-flow/internal/scanner"
+	"pg-bulk-flow/internal/synth"
+)
+
+const (
+	sourceFile      = "file"
+	sourceSynthetic = "synthetic"
+)
+
+// parseSample сколько строк входа разбирается для оценки стоимости парсинга.
+const parseSample = 200_000
+
+var (
+	source    = flag.String("source", sourceFile, "Record source: file (parse -i) or synthetic (generate records in memory, bypassing I/O and parsing)")
+	synRows   = flag.Int64("synthetic-rows", 1_000_000, "Number of records for -source=synthetic")
+	synLenMin = flag.Int("synthetic-len-min", synth.DefaultConfig.MinLen, "Minimum name length in letters for -source=synthetic")
+	synLenMax = flag.Int("synthetic-len-max", synth.DefaultConfig.MaxLen, "Maximum name length in letters for -source=synthetic")
+)
+
+// nameSource источник записей для вставки: сканер входа или генератор.
+type nameSource interface {
+	Scan(ctx context.Context) iter.Seq[model.Name]
+	Lines() int64
+	Err() error
+}
+
+var _ nameSource = &scanner.Scanner{}
+
+// syntheticConfig параметры генератора из флагов: длины распределены вокруг середины
+// диапазона.
+func syntheticConfig() synth.Config {
+	cfg := synth.DefaultConfig
+	cfg.MinLen, cfg.MaxLen = *synLenMin, *synLenMax
+	cfg.MeanLen = float64(*synLenMin+*synLenMax) / 2
+	cfg.StdDevLen = float64(*synLenMax-*synLenMin) / 4
+	return cfg
+}
+
+// syntheticSource отдает записи synth.Generator напрямую инсертеру: без файла, буферов
+// и парсера.
+type syntheticSource struct {
+	gen      *synth.Generator
+	rows     int64
+	nameType model.NameType
+	lines    atomic.Int64
+}
+
+func newSyntheticSource(rows int64, nameType model.NameType) (*syntheticSource, error) {
+	gen, err := synth.New(syntheticConfig(), rows)
+	if err != nil {
+		return nil, err
+	}
+	return &syntheticSource{gen: gen, rows: rows, nameType: nameType}, nil
+}
+
+// Scan implements nameSource. Как и сканер, прекращает выдачу после отмены ctx.
+func (s *syntheticSource) Scan(ctx context.Context) iter.Seq[model.Name] {
+	return func(yield func(model.Name) bool) {
+		done := ctx.Done()
+		for i := range s.rows {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			v := s.gen.Name(s.nameType)
+			v.Line = int(i + 1)
+			s.lines.Store(i + 1)
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Lines implements nameSource.
+func (s *syntheticSource) Lines() int64 {
+	return s.lines.Load()
+}
+
+// Err implements nameSource.
+func (s *syntheticSource) Err() error {
+	return nil
+}
+
+var _ nameSource = &syntheticSource{}
+
+// parseCost оценка того, сколько заняли бы чтение и разбор тех же записей из файла.
+type parseCost struct {
+	Sample   int           `json:"parse_sample_lines"`
+	Parse    time.Duration `json:"parse_estimate"` // на все записи, миллисекунды
+	Overhead float64       `json:"parse_overhead"` // доля разбора в parse_estimate + elapsed
+}
+
+// estimateParseCost разбирает сканером до parseSample строк во входном формате (-format)
+// с теми же параметрами генератора и экстраполирует время на rows загруженных записей.
+// Обычная загрузка без -pipeline последовательно разбирает и вставляет, поэтому ее
+// время оценивается как сумма разбора и elapsed синтетической загрузки.
+func estimateParseCost(ctx context.Context, rows int64, nameType model.NameType, elapsed time.Duration) (parseCost, error) {
+	// генератор того же размера, что и при загрузке: от него зависит длина кода в именах
+	gen, err := synth.New(syntheticConfig(), *synRows)
+	if err != nil {
+		return parseCost{}, err
+	}
+	f, err := newInputFormat(*parseN)
+	if err != nil {
+		return parseCost{}, err
+	}
+
+	n := int(min(rows, parseSample))
+	var data []byte
+	if f.csv == nil {
+		for range n {
+			data = gen.AppendLine(data)
+		}
+	} else {
+		if f.header != nil {
+			data = f.csv.AppendHeader(data)
+			// разрешает колонки до записи строк; сканер разберет заголовок повторно
+			if err := f.csv.ParseHeader(bytes.TrimSuffix(data, []byte{'\n'})); err != nil {
+				return parseCost{}, err
+			}
+		}
+		var count []byte
+		for range n {
+			v := gen.Name(nameType)
+			count = strconv.AppendInt(count[:0], int64(v.Count), 10)
+			data = f.csv.AppendRecord(data, v.Text, string(count), v.Gender.String())
+		}
+	}
+
+	sc := scanner.New(bytes.NewReader(data), nameType, f.parsers[0])
+	sc.SetParseWorkers(f.scannerParsers(), *ordered)
+	sc.SetHeader(f.header)
+	sc.SetMaxLine(*maxLine, *skipLong)
+
+	start := time.Now()
+	for range sc.Scan(ctx) {
+	}
+	sample := time.Since(start)
+	if err := sc.Err(); err != nil {
+		return parseCost{}, err
+	}
+
+	c := parseCost{Sample: n}
+	if n > 0 {
+		c.Parse = time.Duration(float64(sample) * float64(rows) / float64(n))
+	}
+	if total := c.Parse + elapsed; total > 0 {
+		c.Overhead = float64(c.Parse) / float64(total)
+	}
+	c.Parse /= time.Millisecond // to milliseconds
+	return c, nil
+}
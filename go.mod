@@ -5,7 +5,10 @@ go 1.24.2
 require (
 	github.com/jackc/pgx/v5 v5.7.5
 	github.com/joho/godotenv v1.5.1
+	github.com/klauspost/compress v1.18.0
 	github.com/mailru/easyjson v0.9.0
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/text v0.24.0
 )
 
 require (
@@ -15,5 +18,4 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
 )
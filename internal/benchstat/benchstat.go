@@ -0,0 +1,69 @@
+package benchstat
+
+import (
+	"math"
+	"slices"
+)
+
+// Summary сводная статистика по серии измерений.
+type Summary struct {
+	N      int     `json:"n"`
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+// Summarize считает статистику по values. Исходный срез не изменяется.
+func Summarize(values []float64) Summary {
+	n := len(values)
+	if n == 0 {
+		return Summary{}
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(n)
+
+	// выборочное стандартное отклонение
+	var stddev float64
+	if n > 1 {
+		var sq float64
+		for _, v := range sorted {
+			sq += (v - mean) * (v - mean)
+		}
+		stddev = math.Sqrt(sq / float64(n-1))
+	}
+
+	return Summary{
+		N:      n,
+		Min:    sorted[0],
+		Median: Percentile(sorted, 50),
+		P95:    Percentile(sorted, 95),
+		Max:    sorted[n-1],
+		Mean:   mean,
+		StdDev: stddev,
+	}
+}
+
+// Percentile возвращает p-й перцентиль отсортированного по возрастанию среза. Медиана
+// четной выборки — среднее двух центральных значений, остальные перцентили считаются
+// методом ближайшего ранга.
+func Percentile(sorted []float64, p float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if p == 50 && n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	rank := int(math.Ceil(p / 100 * float64(n)))
+	return sorted[min(max(rank, 1), n)-1]
+}
@@ -0,0 +1,44 @@
+package benchstat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   Summary
+	}{
+		{"Empty", nil, Summary{}},
+		{"Single", []float64{5}, Summary{N: 1, Min: 5, Median: 5, P95: 5, Max: 5, Mean: 5}},
+		{"Odd", []float64{3, 1, 2}, Summary{N: 3, Min: 1, Median: 2, P95: 3, Max: 3, Mean: 2, StdDev: 1}},
+		{"Even", []float64{4, 1, 3, 2}, Summary{N: 4, Min: 1, Median: 2.5, P95: 4, Max: 4, Mean: 2.5, StdDev: math.Sqrt(5.0 / 3)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Summarize(tt.values)
+			if math.Abs(got.StdDev-tt.want.StdDev) > 1e-9 {
+				t.Errorf("StdDev = %v, want %v", got.StdDev, tt.want.StdDev)
+			}
+			got.StdDev = tt.want.StdDev
+			if got != tt.want {
+				t.Errorf("Summarize = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]float64, 100)
+	for i := range sorted {
+		sorted[i] = float64(i + 1)
+	}
+	for p, want := range map[float64]float64{50: 50.5, 90: 90, 95: 95, 99: 99, 100: 100, 0: 1} {
+		if got := Percentile(sorted, p); got != want {
+			t.Errorf("Percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// headSize сколько байт от начала файла хэшируется. Хэш всего файла на десятках
+// гигабайт считался бы дольше самой загрузки.
+const headSize = 1 << 20 // 1 MiB
+
+// FileID идентифицирует входной файл. Совпадение всех полей считается признаком того,
+// что файл не менялся.
+type FileID struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Head    string    `json:"head_sha256"` // sha256 первых headSize байт
+}
+
+// Identify вычисляет FileID файла path.
+func Identify(path string) (FileID, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileID{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return FileID{}, err
+	}
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, headSize); err != nil && !errors.Is(err, io.EOF) {
+		return FileID{}, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return FileID{}, err
+	}
+
+	return FileID{
+		Path:    abs,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime().UTC(),
+		Head:    hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// Equal сравнивает идентификаторы. Время сравнивается через time.Time.Equal: после
+// JSON теряется monotonic clock.
+func (id FileID) Equal(o FileID) bool {
+	return id.Path == o.Path && id.Size == o.Size && id.ModTime.Equal(o.ModTime) && id.Head == o.Head
+}
+
+// State содержимое файла checkpoint.
+type State struct {
+	File      FileID    `json:"file"`
+	LastLine  int       `json:"last_committed_line"` // строки до нее включительно загружены
+	Committed int64     `json:"committed"`           // всего зафиксировано записей за все запуски
+	Updated   time.Time `json:"updated"`
+}
+
+// ErrMismatch checkpoint записан для другого или измененного файла.
+var ErrMismatch = errors.New("checkpoint belongs to another input file")
+
+// Load читает состояние из path. Если файла нет, возвращает нулевое состояние для id.
+// Если состояние записано для другого файла, возвращает ErrMismatch.
+func Load(path string, id FileID) (State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{File: id}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return State{}, fmt.Errorf("parse checkpoint: %w", err)
+	}
+	if !st.File.Equal(id) {
+		return State{}, fmt.Errorf("%w: %s", ErrMismatch, st.File.Path)
+	}
+	return st, nil
+}
+
+// Save атомарно записывает состояние в path: через временный файл и rename, чтобы
+// прерывание посреди записи не оставило испорченный checkpoint.
+func Save(path string, st State) error {
+	st.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(st, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // после rename ничего не удалит
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
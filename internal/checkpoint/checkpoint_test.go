@@ -0,0 +1,62 @@
+package checkpoint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "names.jsonl")
+	if err := os.WriteFile(input, []byte("line1\nline2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state := filepath.Join(dir, "state.json")
+
+	id, err := Identify(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := Load(state, id)
+	if err != nil {
+		t.Fatalf("Load missing: %v", err)
+	}
+	if st.LastLine != 0 || !st.File.Equal(id) {
+		t.Fatalf("Load missing = %+v, want empty state", st)
+	}
+
+	st.LastLine, st.Committed = 2, 2
+	if err := Save(state, st); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(state, id)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.LastLine != 2 || got.Committed != 2 {
+		t.Errorf("Load = %+v, want last line 2, committed 2", got)
+	}
+
+	// тот же размер, другое содержимое и время изменения
+	if err := os.WriteFile(input, []byte("LINE1\nLINE2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(input, time.Now(), time.Now().Add(time.Hour))
+	id2, err := Identify(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(state, id2); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Load changed file error = %v, want ErrMismatch", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("temporary files left: %v", entries)
+	}
+}
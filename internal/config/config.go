@@ -12,6 +12,7 @@ type DB struct {
 	Password string
 	Name     string
 	SSLMode  string
+	MaxConns int32 // размер пула соединений (0 — по умолчанию pgxpool)
 }
 
 func (cfg DB) ConnectString() string {
@@ -36,6 +37,7 @@ type Log struct {
 
 type Config struct {
 	PprofEnable bool
+	PprofAddr   string
 	Log         Log
 	DB          DB
 	InputFile   string
@@ -48,6 +50,7 @@ func Load() (*Config, error) {
 
 	return &Config{
 		PprofEnable: ge.Bool("PPROF_ENABLE", !required, false),
+		PprofAddr:   ge.String("PPROF_ADDR", !required, "localhost:6060"),
 		Log: Log{
 			Level:     ge.LogLevel("LOG_LEVEL", !required, slog.LevelInfo),
 			PlainText: ge.Bool("LOG_PLAINTEXT", !required, false),
@@ -17,6 +17,10 @@ func Open(cfg config.DB) (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("parse config failed: %w", err)
 	}
 
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+
 	// Регистрируем типы для каждого нового соединения
 	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
 		return registerEnums(conn, "name_type_enum", "gender_enum")
@@ -0,0 +1,156 @@
+package decompress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format формат сжатия входных данных.
+type Format string
+
+const (
+	FormatNone  Format = "none"
+	FormatGzip  Format = "gzip"
+	FormatZstd  Format = "zstd"
+	FormatBzip2 Format = "bzip2"
+	FormatXZ    Format = "xz"
+)
+
+var magics = []struct {
+	format Format
+	magic  []byte
+}{
+	{FormatGzip, []byte{0x1f, 0x8b}},
+	{FormatZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{FormatBzip2, []byte("BZh")},
+	{FormatXZ, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+// FormatByExt определяет формат по расширению файла. Для неизвестных расширений
+// возвращает пустую строку.
+func FormatByExt(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".gzip":
+		return FormatGzip
+	case ".zst", ".zstd":
+		return FormatZstd
+	case ".bz2":
+		return FormatBzip2
+	case ".xz":
+		return FormatXZ
+	}
+	return ""
+}
+
+// FormatByMagic определяет формат по первым байтам данных.
+func FormatByMagic(head []byte) Format {
+	for _, m := range magics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.format
+		}
+	}
+	return FormatNone
+}
+
+type Stats struct {
+	Format     Format `json:"format,omitempty"`
+	Compressed int64  `json:"compressed_bytes,omitempty"` // прочитано из источника
+	Bytes      int64  `json:"bytes,omitempty"`            // отдано после распаковки
+}
+
+// countingReader считает прочитанные байты. Счетчик атомарный: Stats может
+// вызываться из другой горутины во время чтения.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Reader прозрачно распаковывает входной поток и считает прочитанные байты.
+type Reader struct {
+	raw    countingReader
+	out    countingReader
+	format Format
+	close  func() error
+}
+
+// NewReader оборачивает r распаковщиком. Формат определяется по расширению name, а если
+// оно неизвестно (или name пуст, например для stdin) — по сигнатуре в начале потока.
+func NewReader(r io.Reader, name string) (*Reader, error) {
+	dr := &Reader{raw: countingReader{r: r}}
+
+	br := bufio.NewReader(&dr.raw)
+	format := FormatByExt(name)
+	if format == "" {
+		head, err := br.Peek(6)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		format = FormatByMagic(head)
+	}
+
+	var (
+		dec io.Reader
+		err error
+	)
+	switch format {
+	case FormatNone:
+		dec = br
+	case FormatGzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(br); err == nil {
+			dec, dr.close = zr, zr.Close
+		}
+	case FormatZstd:
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(br); err == nil {
+			dec, dr.close = zr, func() error { zr.Close(); return nil }
+		}
+	case FormatBzip2:
+		dec = bzip2.NewReader(br)
+	case FormatXZ:
+		dec, err = xz.NewReader(br)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+
+	dr.out.r = dec
+	dr.format = format
+	return dr, nil
+}
+
+func (dr *Reader) Read(p []byte) (int, error) {
+	return dr.out.Read(p)
+}
+
+// Close освобождает ресурсы распаковщика. Исходный поток не закрывается.
+func (dr *Reader) Close() error {
+	if dr.close != nil {
+		return dr.close()
+	}
+	return nil
+}
+
+func (dr *Reader) Stats() Stats {
+	return Stats{
+		Format:     dr.format,
+		Compressed: dr.raw.n.Load(),
+		Bytes:      dr.out.n.Load(),
+	}
+}
@@ -0,0 +1,80 @@
+package decompress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestReader(t *testing.T) {
+	const data = `{"count":10,"text":"Иванов","gender":"m"}` + "\n"
+	payload := strings.Repeat(data, 100)
+
+	compress := map[Format]func(w io.Writer) io.WriteCloser{
+		FormatGzip: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		FormatZstd: func(w io.Writer) io.WriteCloser { zw, _ := zstd.NewWriter(w); return zw },
+		FormatXZ:   func(w io.Writer) io.WriteCloser { zw, _ := xz.NewWriter(w); return zw },
+	}
+
+	for format, newWriter := range compress {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newWriter(&buf)
+			io.WriteString(w, payload)
+			w.Close()
+			size := int64(buf.Len())
+
+			// имя без расширения — формат определяется по сигнатуре
+			r, err := NewReader(&buf, "-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != payload {
+				t.Errorf("decompressed data mismatch")
+			}
+
+			st := r.Stats()
+			if st.Format != format || st.Compressed != size || st.Bytes != int64(len(payload)) {
+				t.Errorf("stats = %+v, want {%s %d %d}", st, format, size, len(payload))
+			}
+		})
+	}
+
+	t.Run("none", func(t *testing.T) {
+		r, err := NewReader(strings.NewReader(payload), "names.jsonl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(r)
+		if string(got) != payload || r.Stats().Format != FormatNone {
+			t.Errorf("plain input mismatch, stats = %+v", r.Stats())
+		}
+	})
+}
+
+func TestFormatByExt(t *testing.T) {
+	tests := map[string]Format{
+		"names.jsonl.gz":  FormatGzip,
+		"names.jsonl.zst": FormatZstd,
+		"names.jsonl.bz2": FormatBzip2,
+		"names.jsonl.XZ":  FormatXZ,
+		"names.jsonl":     "",
+		"-":               "",
+	}
+	for name, want := range tests {
+		if got := FormatByExt(name); got != want {
+			t.Errorf("FormatByExt(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package inserter
+
+// AppendArrayLiteral дописывает в dst текстовое представление одномерного массива
+// Postgres ({"a","b"}). Элементы всегда заключаются в кавычки, поэтому пустые строки
+// и NULL в виде текста не требуют особой обработки. nil-массив кодируется как {} —
+// NULL вызывающий код обрабатывает сам.
+func AppendArrayLiteral(dst []byte, ss []string) []byte {
+	dst = append(dst, '{')
+	for i, s := range ss {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, '"')
+		for j := 0; j < len(s); j++ {
+			if s[j] == '"' || s[j] == '\\' {
+				dst = append(dst, '\\')
+			}
+			dst = append(dst, s[j])
+		}
+		dst = append(dst, '"')
+	}
+	return append(dst, '}')
+}
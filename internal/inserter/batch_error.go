@@ -0,0 +1,186 @@
+package inserter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// BatchErrorMode определяет поведение пакетных инсертеров, когда сервер отклоняет пакет.
+//
+//go:generate stringer -type BatchErrorMode -linecomment -output batch_error_mode_string.go
+type BatchErrorMode int8
+
+const (
+	BatchErrorAbort  BatchErrorMode = iota // abort
+	BatchErrorBisect                       // bisect
+)
+
+var AllBatchErrorModes = []BatchErrorMode{BatchErrorAbort, BatchErrorBisect}
+
+func (m BatchErrorMode) IsValid() bool {
+	return slices.Contains(AllBatchErrorModes, m)
+}
+
+func ParseBatchErrorMode(s string) (BatchErrorMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "abort":
+		return BatchErrorAbort, nil
+	case "bisect":
+		return BatchErrorBisect, nil
+	}
+	return 0, fmt.Errorf("unknown batch error mode %q", s)
+}
+
+// IsDataError сообщает, вызвана ли ошибка содержимым строк (классы SQLSTATE 22 — data
+// exception и 23 — integrity constraint violation). Только такие пакеты имеет смысл
+// делить: остальные ошибки (соединение, отмена, синтаксис) повторятся для любой части.
+func IsDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
+
+// DeadLetter запись, отклоненная сервером, вместе с ошибкой.
+type DeadLetter struct {
+	Line       int        `json:"line,omitempty"`
+	Record     model.Name `json:"record"`
+	SQLState   string     `json:"sqlstate,omitempty"`
+	Constraint string     `json:"constraint,omitempty"`
+	Detail     string     `json:"detail,omitempty"`
+	Error      string     `json:"error"`
+}
+
+func NewDeadLetter(v model.Name, err error) DeadLetter {
+	d := DeadLetter{
+		Line:   v.Line,
+		Record: v,
+		Error:  err.Error(),
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		d.SQLState = pgErr.Code
+		d.Constraint = pgErr.ConstraintName
+		d.Detail = pgErr.Detail
+		d.Error = pgErr.Message
+	}
+	return d
+}
+
+// DeadLetterer получает записи, отклоненные сервером.
+type DeadLetterer interface {
+	DeadLetter(d DeadLetter) error
+}
+
+// DeadLetterWriter пишет отклоненные записи в формате JSONL.
+type DeadLetterWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewDeadLetterWriter(w io.Writer) *DeadLetterWriter {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &DeadLetterWriter{
+		w:   bw,
+		enc: enc,
+	}
+}
+
+// DeadLetter implements DeadLetterer.
+func (dw *DeadLetterWriter) DeadLetter(d DeadLetter) error {
+	return dw.enc.Encode(d)
+}
+
+func (dw *DeadLetterWriter) Flush() error {
+	return dw.w.Flush()
+}
+
+var _ DeadLetterer = &DeadLetterWriter{}
+
+// DeadLetterReporter реализуется инсертерами, умеющими отбрасывать плохие записи.
+type DeadLetterReporter interface {
+	DeadLettered() int64
+}
+
+// BatchErrors обработка отклоненных пакетов, общая для пакетных инсертеров.
+// Встраивается в инсертер; нулевое значение соответствует BatchErrorAbort.
+type BatchErrors struct {
+	mode BatchErrorMode
+	dl   DeadLetterer
+	dead int64
+}
+
+// SetOnBatchError задает режим обработки отклоненных пакетов и получателя
+// отброшенных записей (может быть nil, тогда они только подсчитываются).
+func (h *BatchErrors) SetOnBatchError(mode BatchErrorMode, dl DeadLetterer) {
+	h.mode = mode
+	h.dl = dl
+}
+
+// KeepRows сообщает, нужно ли инсертеру сохранять записи пакета для повторной отправки.
+func (h *BatchErrors) KeepRows() bool {
+	return h.mode == BatchErrorBisect
+}
+
+// DeadLettered implements DeadLetterReporter.
+func (h *BatchErrors) DeadLettered() int64 {
+	return h.dead
+}
+
+// Handle обрабатывает отклонение пакета rows с ошибкой err и возвращает количество
+// в итоге вставленных записей. В режиме bisect пакет рекурсивно делится пополам и
+// части отправляются через send, пока плохие записи не будут изолированы; они
+// передаются в DeadLetterer. Иначе возвращает err.
+//
+// Пакет должен отправляться атомарно (одной командой или неявной транзакцией),
+// иначе при повторе часть записей вставится дважды.
+func (h *BatchErrors) Handle(rows []model.Name, err error, send func([]model.Name) error) (int64, error) {
+	if h.mode != BatchErrorBisect || !IsDataError(err) {
+		return 0, err
+	}
+	return h.bisect(rows, err, send)
+}
+
+func (h *BatchErrors) bisect(rows []model.Name, err error, send func([]model.Name) error) (int64, error) {
+	if len(rows) == 1 {
+		h.dead++
+		if h.dl != nil {
+			if err := h.dl.DeadLetter(NewDeadLetter(rows[0], err)); err != nil {
+				return 0, fmt.Errorf("write dead letter: %w", err)
+			}
+		}
+		return 0, nil
+	}
+
+	var inserted int64
+	mid := len(rows) / 2
+	for _, part := range [][]model.Name{rows[:mid], rows[mid:]} {
+		err := send(part)
+		if err == nil {
+			inserted += int64(len(part))
+			continue
+		}
+		if !IsDataError(err) {
+			return inserted, err
+		}
+		n, err := h.bisect(part, err, send)
+		inserted += n
+		if err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
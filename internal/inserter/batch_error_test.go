@@ -0,0 +1,117 @@
+package inserter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type deadLetters []DeadLetter
+
+func (d *deadLetters) DeadLetter(v DeadLetter) error {
+	*d = append(*d, v)
+	return nil
+}
+
+func TestBatchErrorsBisect(t *testing.T) {
+	rows := make([]model.Name, 10)
+	for i := range rows {
+		rows[i].Line = i + 1
+	}
+	bad := []int{3, 4, 9}
+
+	// send атомарен: отклоняет всю часть, если в ней есть плохая запись
+	var sent []int
+	send := func(part []model.Name) error {
+		for _, v := range part {
+			if slices.Contains(bad, v.Line) {
+				return &pgconn.PgError{Code: "23514", ConstraintName: "names_count_check",
+					Detail: "Failing row", Message: "check violation"}
+			}
+		}
+		for _, v := range part {
+			sent = append(sent, v.Line)
+		}
+		return nil
+	}
+
+	var dl deadLetters
+	var h BatchErrors
+	h.SetOnBatchError(BatchErrorBisect, &dl)
+
+	inserted, err := h.Handle(rows, send(rows), send)
+	if err != nil {
+		t.Fatalf("Handle error = %v", err)
+	}
+	if inserted != 7 || h.DeadLettered() != 3 {
+		t.Errorf("inserted/dead = %d/%d, want 7/3", inserted, h.DeadLettered())
+	}
+	slices.Sort(sent)
+	if want := []int{1, 2, 5, 6, 7, 8, 10}; !slices.Equal(sent, want) {
+		t.Errorf("sent = %v, want %v", sent, want)
+	}
+
+	var lines []int
+	for _, d := range dl {
+		lines = append(lines, d.Line)
+		if d.SQLState != "23514" || d.Constraint != "names_count_check" || d.Detail != "Failing row" {
+			t.Errorf("dead letter = %+v", d)
+		}
+	}
+	if !slices.Equal(lines, bad) {
+		t.Errorf("dead letter lines = %v, want %v", lines, bad)
+	}
+}
+
+func TestBatchErrorsAbort(t *testing.T) {
+	dataErr := &pgconn.PgError{Code: "23505"}
+	otherErr := errors.New("conn closed")
+	send := func([]model.Name) error { t.Fatal("unexpected send"); return nil }
+
+	var abort, bisect BatchErrors
+	bisect.SetOnBatchError(BatchErrorBisect, nil)
+
+	for _, tt := range []struct {
+		h   *BatchErrors
+		err error
+	}{
+		{&abort, dataErr},
+		{&bisect, otherErr},
+		{&bisect, &pgconn.PgError{Code: "42P01"}}, // undefined_table
+	} {
+		if _, err := tt.h.Handle(make([]model.Name, 4), tt.err, send); err != tt.err {
+			t.Errorf("Handle(%v) error = %v, want it returned as is", tt.err, err)
+		}
+	}
+}
+
+func TestDeadLetterWriter(t *testing.T) {
+	v := model.Name{Count: 1, Type: model.NameTypeSurname, Text: "Иванов", Gender: model.GenderMale,
+		Ethnic: []string{"slav"}, Line: 7}
+	want := NewDeadLetter(v, &pgconn.PgError{Code: "23514", Message: "check violation"})
+
+	var buf bytes.Buffer
+	dw := NewDeadLetterWriter(&buf)
+	if err := dw.DeadLetter(want); err != nil {
+		t.Fatalf("DeadLetter error = %v", err)
+	}
+	if err := dw.Flush(); err != nil {
+		t.Fatalf("Flush error = %v", err)
+	}
+
+	var got DeadLetter
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", buf.Bytes(), err)
+	}
+	want.Record.Line = 0 // не сериализуется, есть в Line
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package inserter
+
+import (
+	"strings"
+
+	"pg-bulk-flow/internal/model"
+)
+
+// Columns колонки таблицы names, которые заполняют инсертеры, в порядке AppendValues.
+var Columns = []string{"count", "name_type", "name_text", "gender", "fname", "f_form", "m_form", "ethnic"}
+
+// columnList Columns через запятую для подстановки в SQL.
+var columnList = strings.Join(Columns, ", ")
+
+// AppendValues добавляет в dst значения колонок Columns записи v.
+func AppendValues(dst []any, v model.Name) []any {
+	return append(dst, v.Count, v.Type, v.Text, v.Gender, v.FName, v.FForm, v.MForm, v.Ethnic)
+}
@@ -0,0 +1,138 @@
+package inserter
+
+import (
+	"context"
+	"iter"
+
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CommitProgress зафиксированный прогресс загрузки.
+type CommitProgress struct {
+	Commits   int   `json:"commits"`
+	Committed int64 `json:"committed"`           // записей в зафиксированных транзакциях
+	LastLine  int   `json:"last_committed_line"` // номер последней зафиксированной строки входа
+}
+
+// Committer разбивает поток на части по every записей и вставляет каждую часть
+// вложенным инсертером в отдельной транзакции. При ошибке откатывается только
+// текущая часть, все предыдущие остаются в таблице.
+//
+// Вложенный инсертер должен работать через то же соединение conn: транзакция
+// открывается командой BEGIN в сессии, а не передается ему явно.
+type Committer struct {
+	conn     TxBeginner
+	inner    Inserter
+	every    int64
+	progress CommitProgress
+	stats    ConflictStats
+	onCommit func(CommitProgress) error
+}
+
+// TxBeginner часть *pgx.Conn, которая нужна Committer.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+func NewCommitter(conn TxBeginner, inner Inserter, every int64) *Committer {
+	return &Committer{
+		conn:  conn,
+		inner: inner,
+		every: every,
+	}
+}
+
+// SetOnCommit задает функцию, вызываемую после каждой фиксации (например, для
+// сохранения checkpoint). Ее ошибка прерывает вставку.
+func (c *Committer) SetOnCommit(fn func(CommitProgress) error) {
+	c.onCommit = fn
+}
+
+// Rows implements ProgressReporter: возвращает счетчик вложенного инсертера, включая
+// записи текущей, еще не зафиксированной транзакции.
+func (c *Committer) Rows() int64 {
+	if r, ok := c.inner.(ProgressReporter); ok {
+		return r.Rows()
+	}
+	return c.progress.Committed
+}
+
+// Progress возвращает зафиксированный прогресс.
+func (c *Committer) Progress() CommitProgress {
+	return c.progress
+}
+
+// ConflictStats implements ConflictReporter. Учитываются только зафиксированные части.
+func (c *Committer) ConflictStats() ConflictStats {
+	return c.stats
+}
+
+func (c *Committer) insert(ctx context.Context, names iter.Seq[model.Name],
+	insert func(context.Context, iter.Seq[model.Name]) (int64, error),
+) (int64, error) {
+	next, stop := iter.Pull(names)
+	defer stop()
+
+	v, ok := next()
+	for ok {
+		var (
+			n        int64
+			lastLine int
+		)
+		part := func(yield func(model.Name) bool) {
+			for ok && n < c.every {
+				n++
+				lastLine = v.Line
+				if !yield(v) {
+					return
+				}
+				v, ok = next()
+			}
+		}
+
+		tx, err := c.conn.Begin(ctx)
+		if err != nil {
+			return c.progress.Committed, err
+		}
+
+		inserted, err := insert(ctx, part)
+		if err != nil {
+			// контекст может быть уже отменен, а откатить транзакцию нужно все равно
+			tx.Rollback(context.WithoutCancel(ctx))
+			return c.progress.Committed, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return c.progress.Committed, err
+		}
+
+		c.progress.Commits++
+		c.progress.Committed += inserted
+		c.progress.LastLine = lastLine
+		if r, ok := c.inner.(ConflictReporter); ok {
+			c.stats = r.ConflictStats()
+		}
+		if c.onCommit != nil {
+			if err := c.onCommit(c.progress); err != nil {
+				return c.progress.Committed, err
+			}
+		}
+	}
+
+	return c.progress.Committed, nil
+}
+
+func (c *Committer) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	return c.insert(ctx, names, c.inner.Insert)
+}
+
+func (c *Committer) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	return c.insert(ctx, names, c.inner.InsertWithPipeline)
+}
+
+var (
+	_ Inserter         = &Committer{}
+	_ ConflictReporter = &Committer{}
+	_ ProgressReporter = &Committer{}
+)
@@ -0,0 +1,144 @@
+package inserter
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeDB struct {
+	commits, rollbacks int
+}
+
+func (db *fakeDB) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: db}, nil
+}
+
+// fakeInserter запоминает размеры частей и падает на записи со строкой failLine.
+type fakeInserter struct {
+	parts    []int
+	failLine int
+}
+
+func (f *fakeInserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	var n int64
+	for v := range names {
+		if v.Line == f.failLine {
+			return n, errors.New("fail")
+		}
+		n++
+	}
+	f.parts = append(f.parts, int(n))
+	return n, nil
+}
+
+func (f *fakeInserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	return f.Insert(ctx, names)
+}
+
+func lines(n int) iter.Seq[model.Name] {
+	return func(yield func(model.Name) bool) {
+		for i := range n {
+			if !yield(model.Name{Line: i + 2}) { // первая строка — заголовок
+				return
+			}
+		}
+	}
+}
+
+func TestCommitter(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int
+		every     int64
+		failLine  int
+		parts     []int
+		progress  CommitProgress
+		rollbacks int
+		wantErr   bool
+	}{
+		{
+			name:     "Even",
+			rows:     6,
+			every:    3,
+			parts:    []int{3, 3},
+			progress: CommitProgress{Commits: 2, Committed: 6, LastLine: 7},
+		},
+		{
+			name:     "ShortLast",
+			rows:     7,
+			every:    3,
+			parts:    []int{3, 3, 1},
+			progress: CommitProgress{Commits: 3, Committed: 7, LastLine: 8},
+		},
+		{
+			name:  "Empty",
+			rows:  0,
+			every: 3,
+		},
+		{
+			name:      "Fail",
+			rows:      7,
+			every:     3,
+			failLine:  6, // пятая запись, вторая часть
+			parts:     []int{3},
+			progress:  CommitProgress{Commits: 1, Committed: 3, LastLine: 4},
+			rollbacks: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			inner := &fakeInserter{failLine: tt.failLine}
+			c := NewCommitter(db, inner, tt.every)
+			var saved []int
+			c.SetOnCommit(func(p CommitProgress) error {
+				saved = append(saved, p.LastLine)
+				return nil
+			})
+
+			n, err := c.Insert(context.Background(), lines(tt.rows))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Insert error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != tt.progress.Committed {
+				t.Errorf("Insert = %d, want %d", n, tt.progress.Committed)
+			}
+			if !slices.Equal(inner.parts, tt.parts) {
+				t.Errorf("parts = %v, want %v", inner.parts, tt.parts)
+			}
+			if got := c.Progress(); got != tt.progress {
+				t.Errorf("Progress = %+v, want %+v", got, tt.progress)
+			}
+			if len(saved) != tt.progress.Commits || len(saved) > 0 && saved[len(saved)-1] != tt.progress.LastLine {
+				t.Errorf("onCommit lines = %v, want %d calls ending with %d", saved, tt.progress.Commits, tt.progress.LastLine)
+			}
+			if db.commits != tt.progress.Commits || db.rollbacks != tt.rollbacks {
+				t.Errorf("commits/rollbacks = %d/%d, want %d/%d",
+					db.commits, db.rollbacks, tt.progress.Commits, tt.rollbacks)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package inserter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ConflictMode определяет поведение при конфликте по уникальному ключу
+// (name_text, name_type, gender).
+//
+//go:generate stringer -type ConflictMode -linecomment -output conflict_mode_string.go
+type ConflictMode int8
+
+const (
+	ConflictError   ConflictMode = iota // error
+	ConflictSkip                        // skip
+	ConflictSum                         // sum
+	ConflictReplace                     // replace
+)
+
+var AllConflictModes = []ConflictMode{ConflictError, ConflictSkip, ConflictSum, ConflictReplace}
+
+func (m ConflictMode) IsValid() bool {
+	return slices.Contains(AllConflictModes, m)
+}
+
+func ParseConflictMode(s string) (ConflictMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "error":
+		return ConflictError, nil
+	case "skip":
+		return ConflictSkip, nil
+	case "sum":
+		return ConflictSum, nil
+	case "replace":
+		return ConflictReplace, nil
+	}
+	return 0, fmt.Errorf("unknown conflict mode %q", s)
+}
+
+// maxCount верхняя граница колонки count (integer): сумма счетчиков насыщается на ней,
+// а не обрывает всю вставку ошибкой переполнения.
+const maxCount = `2147483647`
+
+// Clause возвращает ON CONFLICT выражение для INSERT. Для ConflictError — пустую строку.
+func (m ConflictMode) Clause() string {
+	const target = ` ON CONFLICT (name_text, name_type, gender)`
+	switch m {
+	case ConflictSkip:
+		return target + ` DO NOTHING`
+	case ConflictSum:
+		return target + ` DO UPDATE SET count = least(names.count::bigint + EXCLUDED.count, ` + maxCount + `)`
+	case ConflictReplace:
+		return target + ` DO UPDATE SET count = EXCLUDED.count, fname = EXCLUDED.fname,` +
+			` f_form = EXCLUDED.f_form, m_form = EXCLUDED.m_form, ethnic = EXCLUDED.ethnic`
+	}
+	return ""
+}
+
+// UpsertSQL возвращает запрос, вставляющий строки из from (должен давать колонки Columns
+// и порядковый номер ord) с учетом режима конфликта.
+// Запрос возвращает одну строку — количество действительно вставленных записей.
+//
+// Повторяющиеся ключи внутри одного запроса заранее схлопываются: DO UPDATE не может
+// изменить одну строку дважды за команду. Для sum счетчики складываются (остальные
+// колонки берутся через max, при конфликте с существующей строкой они не меняются),
+// для replace побеждает последняя запись. Суммы ограничиваются maxCount.
+func (m ConflictMode) UpsertSQL(from string) string {
+	var sel string
+	switch m {
+	case ConflictSum:
+		sel = `SELECT least(sum(count), ` + maxCount + `)::int, name_type, name_text, gender,` +
+			` max(fname), max(f_form), max(m_form), max(ethnic) FROM ` + from +
+			` GROUP BY name_type, name_text, gender`
+	case ConflictReplace:
+		sel = `SELECT DISTINCT ON (name_type, name_text, gender) ` + columnList + ` FROM ` + from +
+			` ORDER BY name_type, name_text, gender, ord DESC`
+	default:
+		sel = `SELECT ` + columnList + ` FROM ` + from
+	}
+	return upsert(sel + m.Clause())
+}
+
+// UpsertValuesSQL то же что UpsertSQL, но для вставки одной записи из параметров $1..$8.
+func (m ConflictMode) UpsertValuesSQL() string {
+	return upsert(`VALUES ($1, $2, $3, $4, $5, $6, $7, $8)` + m.Clause())
+}
+
+// upsert оборачивает INSERT в CTE для подсчета вставленных строк. Строка, вставленная
+// текущей транзакцией, имеет xmax = 0; обновленная через DO UPDATE — нет.
+func upsert(body string) string {
+	return `WITH ins AS (INSERT INTO names (` + columnList + `) ` + body +
+		` RETURNING xmax = 0 AS inserted) SELECT count(*) FILTER (WHERE inserted) FROM ins`
+}
+
+// ConflictStats разбивает обработанные записи по результату вставки.
+type ConflictStats struct {
+	Inserted int64 `json:"inserted,omitempty"`
+	Updated  int64 `json:"updated,omitempty"`
+	Skipped  int64 `json:"skipped,omitempty"`
+}
+
+// Add учитывает n обработанных записей, из которых inserted были вставлены. Остальные
+// записываются в updated или skipped в зависимости от режима.
+func (s *ConflictStats) Add(m ConflictMode, n, inserted int64) {
+	s.Inserted += inserted
+	switch m {
+	case ConflictSkip:
+		s.Skipped += n - inserted
+	case ConflictSum, ConflictReplace:
+		s.Updated += n - inserted
+	}
+}
+
+// ConflictReporter реализуется инсерторами, поддерживающими режим ON CONFLICT.
+type ConflictReporter interface {
+	ConflictStats() ConflictStats
+}
@@ -11,17 +11,19 @@ import (
 )
 
 type source struct {
-	next   func() (model.Name, bool)
-	stop   func()
-	values []any
+	next     func() (model.Name, bool)
+	stop     func()
+	values   []any
+	progress *inserter.Progress
 }
 
-func newSource(names iter.Seq[model.Name]) *source {
+func newSource(names iter.Seq[model.Name], progress *inserter.Progress) *source {
 	next, stop := iter.Pull(names)
 	return &source{
-		next:   next,
-		stop:   stop,
-		values: make([]any, 0, 4),
+		next:     next,
+		stop:     stop,
+		values:   make([]any, 0, len(inserter.Columns)),
+		progress: progress,
 	}
 }
 
@@ -31,7 +33,8 @@ func (s *source) Next() bool {
 		s.values = s.values[:0]
 		return false
 	}
-	s.values = append(s.values[:0], v.Count, v.Type, v.Text, v.Gender)
+	s.values = inserter.AppendValues(s.values[:0], v)
+	s.progress.AddRows(1)
 	return true
 }
 
@@ -50,32 +53,74 @@ func (s *source) close() {
 var _ pgx.CopyFromSource = &source{}
 
 type Inserter struct {
-	conn *pgx.Conn
+	inserter.Progress
+	conn       *pgx.Conn
+	onConflict inserter.ConflictMode
+	stats      inserter.ConflictStats
 }
 
-func New(conn *pgx.Conn) *Inserter {
-	return &Inserter{conn}
+func New(conn *pgx.Conn, onConflict inserter.ConflictMode) *Inserter {
+	return &Inserter{
+		conn:       conn,
+		onConflict: onConflict,
+	}
+}
+
+// ConflictStats implements inserter.ConflictReporter.
+func (ins *Inserter) ConflictStats() inserter.ConflictStats {
+	return ins.stats
+}
+
+// copyFrom выполняет COPY напрямую в names, либо, если задан режим конфликта, через
+// временную таблицу с последующим INSERT ... ON CONFLICT.
+func (ins *Inserter) copyFrom(ctx context.Context, src pgx.CopyFromSource) (int64, error) {
+	if ins.onConflict == inserter.ConflictError {
+		n, err := ins.conn.CopyFrom(ctx, pgx.Identifier{"names"}, inserter.Columns, src)
+		ins.stats.Add(ins.onConflict, n, n)
+		return n, err
+	}
+
+	if err := inserter.CreateStaging(ctx, ins.conn); err != nil {
+		return 0, err
+	}
+	defer inserter.DropStaging(ctx, ins.conn)
+
+	n, err := ins.conn.CopyFrom(ctx, pgx.Identifier{inserter.StagingTable}, inserter.Columns, src)
+	if err != nil {
+		return 0, err
+	}
+
+	inserted, err := inserter.MergeStaging(ctx, ins.conn, ins.onConflict)
+	if err != nil {
+		return 0, err
+	}
+
+	ins.stats.Add(ins.onConflict, n, inserted)
+	return n, nil
 }
 
 func (ins *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
-	src := newSource(names)
+	src := newSource(names, &ins.Progress)
 	defer src.close()
 
-	return ins.conn.CopyFrom(ctx, pgx.Identifier{"names"},
-		[]string{"count", "name_type", "name_text", "gender"}, src)
+	return ins.copyFrom(ctx, src)
 }
 
 type asyncSource struct {
-	ch     chan model.Name
-	cancel chan struct{}
-	values []any
+	ch       chan model.Name
+	cancel   chan struct{}
+	exited   chan struct{}
+	values   []any
+	progress *inserter.Progress
 }
 
-func newAsyncSource(names iter.Seq[model.Name]) *asyncSource {
+func newAsyncSource(names iter.Seq[model.Name], progress *inserter.Progress) *asyncSource {
 	ch := make(chan model.Name)
 	cancel := make(chan struct{})
+	exited := make(chan struct{})
 
 	go func() {
+		defer close(exited)
 		defer close(ch)
 		for v := range names {
 			select {
@@ -87,9 +132,11 @@ func newAsyncSource(names iter.Seq[model.Name]) *asyncSource {
 	}()
 
 	return &asyncSource{
-		ch:     ch,
-		cancel: cancel,
-		values: make([]any, 0, 4),
+		ch:       ch,
+		cancel:   cancel,
+		exited:   exited,
+		values:   make([]any, 0, len(inserter.Columns)),
+		progress: progress,
 	}
 }
 
@@ -104,7 +151,8 @@ func (a *asyncSource) Next() bool {
 	if !ok {
 		return false
 	}
-	a.values = append(a.values[:0], v.Count, v.Type, v.Text, v.Gender)
+	a.values = inserter.AppendValues(a.values[:0], v)
+	a.progress.AddRows(1)
 	return true
 }
 
@@ -113,18 +161,24 @@ func (a *asyncSource) Values() ([]any, error) {
 	return a.values, nil
 }
 
+// close останавливает горутину чтения и дожидается ее выхода: после возврата names
+// больше не выполняется, и вызывающий может продолжать с тем же итератором.
 func (a *asyncSource) close() {
 	close(a.cancel)
+	<-a.exited
 }
 
 var _ pgx.CopyFromSource = &asyncSource{}
 
 func (ins *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
-	src := newAsyncSource(names)
+	src := newAsyncSource(names, &ins.Progress)
 	defer src.close()
-	
-	return ins.conn.CopyFrom(ctx, pgx.Identifier{"names"},
-		[]string{"count", "name_type", "name_text", "gender"}, src)
+
+	return ins.copyFrom(ctx, src)
 }
 
-var _ inserter.Inserter = &Inserter{}
+var (
+	_ inserter.Inserter         = &Inserter{}
+	_ inserter.ConflictReporter = &Inserter{}
+	_ inserter.ProgressReporter = &Inserter{}
+)
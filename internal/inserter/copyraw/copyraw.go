@@ -0,0 +1,251 @@
+package copyraw
+
+import (
+	"context"
+	"io"
+	"iter"
+	"strings"
+
+	"pg-bulk-flow/internal/inserter"
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// bufSize размер порции данных, отдаваемой в COPY за раз.
+const bufSize = 64 << 10
+
+// encoder кодирует записи из next порциями примерно по bufSize байт.
+type encoder struct {
+	format   Format
+	next     func() (model.Name, bool)
+	scratch  []byte
+	eof      bool
+	progress *inserter.Progress
+}
+
+// fill дописывает в buf записи, пока размер не достигнет bufSize или записи не
+// кончатся; в последнюю порцию добавляется trailer. Записи учитываются в progress
+// порцией целиком.
+func (e *encoder) fill(buf []byte) ([]byte, error) {
+	var n int64
+	defer func() { e.progress.AddRows(n) }()
+
+	for len(buf) < bufSize {
+		v, ok := e.next()
+		if !ok {
+			e.eof = true
+			return append(buf, e.format.trailer()...), nil
+		}
+		var err error
+		if buf, e.scratch, err = e.format.appendRow(buf, e.scratch, v); err != nil {
+			return buf, err
+		}
+		n++
+	}
+	return buf, nil
+}
+
+// reader io.Reader поверх encoder с одним переиспользуемым буфером: после прогрева
+// кодирование записи не аллоцирует.
+type reader struct {
+	enc encoder
+	buf []byte
+	pos int
+}
+
+func newReader(format Format, next func() (model.Name, bool), progress *inserter.Progress) *reader {
+	buf := make([]byte, 0, bufSize+bufSize/4)
+	return &reader{
+		enc: encoder{format: format, next: next, progress: progress},
+		buf: append(buf, format.header()...),
+	}
+}
+
+// Read implements io.Reader.
+func (r *reader) Read(p []byte) (int, error) {
+	if r.pos == len(r.buf) {
+		if r.enc.eof {
+			return 0, io.EOF
+		}
+		var err error
+		r.buf, r.pos = r.buf[:0], 0
+		if r.buf, err = r.enc.fill(r.buf); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+var _ io.Reader = &reader{}
+
+type chunk struct {
+	buf []byte
+	err error
+}
+
+// asyncReader получает закодированные порции от горутины, читающей записи.
+// Буферы возвращаются в free для повторного использования.
+type asyncReader struct {
+	ch     <-chan chunk
+	free   chan<- []byte
+	cancel chan struct{}
+	exited chan struct{}
+	buf    []byte
+	pos    int
+}
+
+func newAsyncReader(format Format, names iter.Seq[model.Name], progress *inserter.Progress) *asyncReader {
+	ch := make(chan chunk)
+	free := make(chan []byte, 2)
+	cancel := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		defer close(ch)
+
+		next, stop := iter.Pull(names)
+		defer stop()
+
+		enc := encoder{format: format, next: next, progress: progress}
+		buf := append(make([]byte, 0, bufSize+bufSize/4), format.header()...)
+		for !enc.eof {
+			var err error
+			buf, err = enc.fill(buf)
+			select {
+			case ch <- chunk{buf, err}:
+			case <-cancel:
+				return
+			}
+			if err != nil {
+				return
+			}
+			select {
+			case buf = <-free:
+				buf = buf[:0]
+			default:
+				buf = make([]byte, 0, bufSize+bufSize/4)
+			}
+		}
+	}()
+
+	return &asyncReader{
+		ch:     ch,
+		free:   free,
+		cancel: cancel,
+		exited: exited,
+	}
+}
+
+// Read implements io.Reader.
+func (a *asyncReader) Read(p []byte) (int, error) {
+	if a.pos == len(a.buf) {
+		if a.buf != nil {
+			select {
+			case a.free <- a.buf:
+			default:
+			}
+			a.buf = nil
+		}
+		c, ok := <-a.ch
+		if !ok {
+			return 0, io.EOF
+		}
+		if c.err != nil {
+			return 0, c.err
+		}
+		a.buf, a.pos = c.buf, 0
+	}
+	n := copy(p, a.buf[a.pos:])
+	a.pos += n
+	return n, nil
+}
+
+// close останавливает горутину кодирования и дожидается ее выхода: после возврата
+// names больше не выполняется.
+func (a *asyncReader) close() {
+	close(a.cancel)
+	<-a.exited
+}
+
+var _ io.Reader = &asyncReader{}
+
+// Inserter загружает записи через COPY ... FROM STDIN в формате binary, text или csv,
+// кодируя строки самостоятельно, без pgx.CopyFromSource и кодеков pgx.
+type Inserter struct {
+	inserter.Progress
+	conn       *pgx.Conn
+	format     Format
+	onConflict inserter.ConflictMode
+	stats      inserter.ConflictStats
+}
+
+func New(conn *pgx.Conn, format Format, onConflict inserter.ConflictMode) *Inserter {
+	return &Inserter{
+		conn:       conn,
+		format:     format,
+		onConflict: onConflict,
+	}
+}
+
+// ConflictStats implements inserter.ConflictReporter.
+func (ins *Inserter) ConflictStats() inserter.ConflictStats {
+	return ins.stats
+}
+
+func (ins *Inserter) copySQL(table string) string {
+	return `COPY ` + table + ` (` + strings.Join(inserter.Columns, ", ") + `) FROM STDIN (FORMAT ` + string(ins.format) + `)`
+}
+
+// copyFrom выполняет COPY напрямую в names, либо, если задан режим конфликта, через
+// временную таблицу с последующим INSERT ... ON CONFLICT.
+func (ins *Inserter) copyFrom(ctx context.Context, r io.Reader) (int64, error) {
+	if ins.onConflict == inserter.ConflictError {
+		tag, err := ins.conn.PgConn().CopyFrom(ctx, r, ins.copySQL("names"))
+		n := tag.RowsAffected()
+		ins.stats.Add(ins.onConflict, n, n)
+		return n, err
+	}
+
+	if err := inserter.CreateStaging(ctx, ins.conn); err != nil {
+		return 0, err
+	}
+	defer inserter.DropStaging(ctx, ins.conn)
+
+	tag, err := ins.conn.PgConn().CopyFrom(ctx, r, ins.copySQL(inserter.StagingTable))
+	if err != nil {
+		return 0, err
+	}
+	n := tag.RowsAffected()
+
+	inserted, err := inserter.MergeStaging(ctx, ins.conn, ins.onConflict)
+	if err != nil {
+		return 0, err
+	}
+
+	ins.stats.Add(ins.onConflict, n, inserted)
+	return n, nil
+}
+
+func (ins *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	next, stop := iter.Pull(names)
+	defer stop()
+
+	return ins.copyFrom(ctx, newReader(ins.format, next, &ins.Progress))
+}
+
+func (ins *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	r := newAsyncReader(ins.format, names, &ins.Progress)
+	defer r.close()
+
+	return ins.copyFrom(ctx, r)
+}
+
+var (
+	_ inserter.Inserter         = &Inserter{}
+	_ inserter.ConflictReporter = &Inserter{}
+	_ inserter.ProgressReporter = &Inserter{}
+)
@@ -0,0 +1,150 @@
+package copyraw
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+
+	"pg-bulk-flow/internal/inserter"
+	"pg-bulk-flow/internal/model"
+)
+
+func TestAppendName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   model.Name
+		want []byte
+	}{
+		{
+			name: "NullEthnic",
+			in:   model.Name{Count: 258, Type: model.NameTypeSurname, Text: "Ли", Gender: model.GenderMale},
+			want: slices.Concat(
+				[]byte{0, 8},
+				[]byte{0, 0, 0, 4, 0, 0, 1, 2},
+				[]byte{0, 0, 0, 7}, []byte("surname"),
+				[]byte{0, 0, 0, 4}, []byte("Ли"),
+				[]byte{0, 0, 0, 4}, []byte("male"),
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 0},
+				[]byte{0xff, 0xff, 0xff, 0xff},
+			),
+		},
+		{
+			name: "Ethnic",
+			in: model.Name{Count: 1, Type: model.NameTypeName, Text: "A", Gender: model.GenderUnknown,
+				FForm: "B", Ethnic: []string{"slav", ""}},
+			want: slices.Concat(
+				[]byte{0, 8},
+				[]byte{0, 0, 0, 4, 0, 0, 0, 1},
+				[]byte{0, 0, 0, 4}, []byte("name"),
+				[]byte{0, 0, 0, 1}, []byte("A"),
+				[]byte{0, 0, 0, 7}, []byte("unknown"),
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 1}, []byte("B"),
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 32},
+				[]byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 25, 0, 0, 0, 2, 0, 0, 0, 1},
+				[]byte{0, 0, 0, 4}, []byte("slav"),
+				[]byte{0, 0, 0, 0},
+			),
+		},
+		{
+			name: "EmptyEthnic",
+			in:   model.Name{Count: 1, Type: model.NameTypeName, Text: "A", Ethnic: []string{}},
+			want: slices.Concat(
+				[]byte{0, 8},
+				[]byte{0, 0, 0, 4, 0, 0, 0, 1},
+				[]byte{0, 0, 0, 4}, []byte("name"),
+				[]byte{0, 0, 0, 1}, []byte("A"),
+				[]byte{0, 0, 0, 7}, []byte("unknown"),
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 0},
+				[]byte{0, 0, 0, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 25},
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appendNameBinary(nil, tt.in)
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("appendNameBinary =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendRowInvalid(t *testing.T) {
+	for _, f := range []Format{FormatBinary, FormatText, FormatCSV} {
+		if _, _, err := f.appendRow(nil, nil, model.Name{Type: 100}); err == nil {
+			t.Errorf("%s: want error for invalid name type", f)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	v := model.Name{Count: 1, Type: model.NameTypeName, Text: "Иван", Gender: model.GenderMale}
+	row := appendNameBinary(nil, v)
+
+	const n = 10000 // больше одной порции
+	left := n
+	progress := new(inserter.Progress)
+	r := newReader(FormatBinary, func() (model.Name, bool) {
+		if left == 0 {
+			return model.Name{}, false
+		}
+		left--
+		return v, true
+	}, progress)
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll error = %v", err)
+	}
+	want := slices.Concat(binaryHeader, bytes.Repeat(row, n), binaryTrailer)
+	if !bytes.Equal(got, want) {
+		t.Errorf("stream length = %d, want %d", len(got), len(want))
+	}
+	if rows := progress.Rows(); rows != n {
+		t.Errorf("progress rows = %d, want %d", rows, n)
+	}
+}
+
+func TestAppendNameAllocs(t *testing.T) {
+	v := model.Name{Count: 1, Type: model.NameTypeName, Text: "Иван", Gender: model.GenderMale,
+		FForm: "Ивана", Ethnic: []string{"slav"}}
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(1000, func() {
+		buf = appendNameBinary(buf[:0], v)
+	})
+	if allocs != 0 {
+		t.Errorf("appendNameBinary allocs = %v, want 0", allocs)
+	}
+}
+
+func TestAsyncReaderClose(t *testing.T) {
+	v := model.Name{Count: 1, Type: model.NameTypeName, Text: "Иван", Gender: model.GenderMale}
+
+	var running bool
+	names := func(yield func(model.Name) bool) {
+		running = true
+		defer func() { running = false }()
+		for {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+
+	r := newAsyncReader(FormatBinary, names, new(inserter.Progress))
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("Read error = %v", err)
+	}
+	r.close()
+	// без ожидания горутины здесь гонка: race detector ее увидит
+	if running {
+		t.Error("names still running after close")
+	}
+}
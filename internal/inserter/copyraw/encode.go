@@ -0,0 +1,124 @@
+package copyraw
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"pg-bulk-flow/internal/model"
+)
+
+// Format формат потока COPY.
+type Format string
+
+const (
+	FormatBinary Format = "binary"
+	FormatText   Format = "text"
+	FormatCSV    Format = "csv"
+)
+
+// appendRow кодирует запись в формате f в порядке inserter.Columns. scratch —
+// буфер для промежуточных значений (литерала массива), возвращается для переиспользования.
+func (f Format) appendRow(dst, scratch []byte, v model.Name) ([]byte, []byte, error) {
+	if !v.Type.IsValid() {
+		return dst, scratch, fmt.Errorf("invalid name type value %v", v.Type)
+	}
+	if !v.Gender.IsValid() {
+		return dst, scratch, fmt.Errorf("invalid gender %v", v.Gender)
+	}
+	switch f {
+	case FormatText:
+		dst, scratch = appendNameText(dst, scratch, v)
+	case FormatCSV:
+		dst, scratch = appendNameCSV(dst, scratch, v)
+	default:
+		dst = appendNameBinary(dst, v)
+	}
+	return dst, scratch, nil
+}
+
+// header заголовок потока (есть только у binary).
+func (f Format) header() []byte {
+	if f == FormatBinary {
+		return binaryHeader
+	}
+	return nil
+}
+
+// trailer признак конца потока (есть только у binary; для text и csv достаточно
+// CopyDone протокола).
+func (f Format) trailer() []byte {
+	if f == FormatBinary {
+		return binaryTrailer
+	}
+	return nil
+}
+
+// Формат COPY BINARY: https://www.postgresql.org/docs/current/sql-copy.html#id-1.9.3.55.9.4
+
+// binaryHeader сигнатура, флаги и длина расширения заголовка.
+var binaryHeader = []byte("PGCOPY\n\xff\r\n\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+
+// binaryTrailer признак конца данных: количество полей -1.
+var binaryTrailer = []byte{0xff, 0xff}
+
+// numFields количество колонок inserter.Columns.
+const numFields = 8
+
+// textOID oid типа text — тип элементов массива ethnic.
+const textOID = 25
+
+// appendNameBinary кодирует запись в двоичный формат COPY в порядке inserter.Columns.
+// Кодирование не аллоцирует, если у dst достаточная емкость. Значения enum должны
+// быть проверены вызывающим кодом.
+func appendNameBinary(dst []byte, v model.Name) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, numFields)
+
+	dst = binary.BigEndian.AppendUint32(dst, 4)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(v.Count))
+
+	// двоичное представление enum — его метка
+	dst = appendText(dst, v.Type.String())
+	dst = appendText(dst, v.Text)
+	dst = appendText(dst, v.Gender.String())
+	dst = appendText(dst, v.FName)
+	dst = appendText(dst, v.FForm)
+	dst = appendText(dst, v.MForm)
+	return appendTextArray(dst, v.Ethnic)
+}
+
+func appendText(dst []byte, s string) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(s)))
+	return append(dst, s...)
+}
+
+// appendTextArray кодирует одномерный text[]; nil кодируется как NULL.
+func appendTextArray(dst []byte, ss []string) []byte {
+	if ss == nil {
+		return binary.BigEndian.AppendUint32(dst, 0xffffffff) // -1: NULL
+	}
+
+	size := 12 // ndim, флаг NULL-элементов, oid элементов
+	if len(ss) > 0 {
+		size += 8 // размер и нижняя граница измерения
+	}
+	for _, s := range ss {
+		size += 4 + len(s)
+	}
+
+	dst = binary.BigEndian.AppendUint32(dst, uint32(size))
+	if len(ss) == 0 {
+		dst = binary.BigEndian.AppendUint32(dst, 0) // пустой массив не имеет измерений
+	} else {
+		dst = binary.BigEndian.AppendUint32(dst, 1)
+	}
+	dst = binary.BigEndian.AppendUint32(dst, 0)
+	dst = binary.BigEndian.AppendUint32(dst, textOID)
+	if len(ss) > 0 {
+		dst = binary.BigEndian.AppendUint32(dst, uint32(len(ss)))
+		dst = binary.BigEndian.AppendUint32(dst, 1)
+	}
+	for _, s := range ss {
+		dst = appendText(dst, s)
+	}
+	return dst
+}
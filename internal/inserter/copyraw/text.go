@@ -0,0 +1,97 @@
+package copyraw
+
+import (
+	"strconv"
+
+	"pg-bulk-flow/internal/inserter"
+	"pg-bulk-flow/internal/model"
+)
+
+// Текстовые форматы COPY: https://www.postgresql.org/docs/current/sql-copy.html#id-1.9.3.55.9.2
+
+// textNull представление NULL в формате text.
+const textNull = `\N`
+
+// appendNameText кодирует запись в формат text: поля через табуляцию, строка
+// завершается переводом строки.
+func appendNameText(dst, scratch []byte, v model.Name) ([]byte, []byte) {
+	dst = strconv.AppendInt(dst, int64(v.Count), 10)
+	dst = append(dst, '\t')
+	dst = append(dst, v.Type.String()...)
+	dst = append(dst, '\t')
+	dst = appendEscaped(dst, v.Text)
+	dst = append(dst, '\t')
+	dst = append(dst, v.Gender.String()...)
+	dst = append(dst, '\t')
+	dst = appendEscaped(dst, v.FName)
+	dst = append(dst, '\t')
+	dst = appendEscaped(dst, v.FForm)
+	dst = append(dst, '\t')
+	dst = appendEscaped(dst, v.MForm)
+	dst = append(dst, '\t')
+	if v.Ethnic == nil {
+		dst = append(dst, textNull...)
+	} else {
+		scratch = inserter.AppendArrayLiteral(scratch[:0], v.Ethnic)
+		dst = appendEscaped(dst, scratch)
+	}
+	return append(dst, '\n'), scratch
+}
+
+// appendEscaped экранирует значение для формата text: обратная косая черта,
+// разделитель и переводы строк иначе были бы прочитаны как разметка. Строка `\N`
+// после экранирования становится `\\N` и не путается с NULL.
+func appendEscaped[T string | []byte](dst []byte, s T) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			dst = append(dst, '\\', '\\')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// appendNameCSV кодирует запись в формат csv с параметрами по умолчанию: разделитель
+// запятая, NULL — пустое поле без кавычек. Поэтому строки всегда заключаются в
+// кавычки: так пустая строка отличается от NULL, а `\.` не принимается за конец данных.
+func appendNameCSV(dst, scratch []byte, v model.Name) ([]byte, []byte) {
+	dst = strconv.AppendInt(dst, int64(v.Count), 10)
+	dst = append(dst, ',')
+	dst = append(dst, v.Type.String()...)
+	dst = append(dst, ',')
+	dst = appendQuoted(dst, v.Text)
+	dst = append(dst, ',')
+	dst = append(dst, v.Gender.String()...)
+	dst = append(dst, ',')
+	dst = appendQuoted(dst, v.FName)
+	dst = append(dst, ',')
+	dst = appendQuoted(dst, v.FForm)
+	dst = append(dst, ',')
+	dst = appendQuoted(dst, v.MForm)
+	dst = append(dst, ',')
+	if v.Ethnic != nil {
+		scratch = inserter.AppendArrayLiteral(scratch[:0], v.Ethnic)
+		dst = appendQuoted(dst, scratch)
+	}
+	return append(dst, '\n'), scratch
+}
+
+// appendQuoted заключает значение в кавычки, удваивая кавычки внутри.
+func appendQuoted[T string | []byte](dst []byte, s T) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			dst = append(dst, '"')
+		}
+		dst = append(dst, s[i])
+	}
+	return append(dst, '"')
+}
@@ -0,0 +1,55 @@
+package copyraw
+
+import (
+	"testing"
+
+	"pg-bulk-flow/internal/model"
+)
+
+func TestAppendRowText(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        model.Name
+		text, csv string
+	}{
+		{
+			name: "Plain",
+			in:   model.Name{Count: 5, Type: model.NameTypeSurname, Text: "Иванов", Gender: model.GenderMale},
+			text: "5\tsurname\tИванов\tmale\t\t\t\t\\N\n",
+			csv:  "5,surname,\"Иванов\",male,\"\",\"\",\"\",\n",
+		},
+		{
+			name: "Escaping",
+			in: model.Name{Count: 1, Type: model.NameTypeName, Text: "a\tb\\c\nd\re\"f,g",
+				Gender: model.GenderUnknown, FName: `\N`},
+			text: "1\tname\ta\\tb\\\\c\\nd\\re\"f,g\tunknown\t\\\\N\t\t\t\\N\n",
+			csv:  "1,name,\"a\tb\\c\nd\re\"\"f,g\",unknown,\"\\N\",\"\",\"\",\n",
+		},
+		{
+			name: "Ethnic",
+			in: model.Name{Count: 1, Type: model.NameTypeName, Text: "A", Gender: model.GenderFemale,
+				Ethnic: []string{"slav", `q"\`}},
+			text: "1\tname\tA\tfemale\t\t\t\t{\"slav\",\"q\\\\\"\\\\\\\\\"}\n",
+			csv:  "1,name,\"A\",female,\"\",\"\",\"\",\"{\"\"slav\"\",\"\"q\\\"\"\\\\\"\"}\"\n",
+		},
+		{
+			name: "EmptyEthnic",
+			in:   model.Name{Count: 1, Type: model.NameTypeName, Text: "A", Ethnic: []string{}},
+			text: "1\tname\tA\tunknown\t\t\t\t{}\n",
+			csv:  "1,name,\"A\",unknown,\"\",\"\",\"\",\"{}\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for f, want := range map[Format]string{FormatText: tt.text, FormatCSV: tt.csv} {
+				got, _, err := f.appendRow(nil, nil, tt.in)
+				if err != nil {
+					t.Fatalf("%s: appendRow error = %v", f, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s: appendRow = %q, want %q", f, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package parallelcopy
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"pg-bulk-flow/internal/inserter"
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// chunkSize количество записей, передаваемых воркеру за раз. Передавать по одной
+// записи через канал слишком дорого.
+const chunkSize = 1024
+
+// source читает чанки из общего канала и отдает их в CopyFrom по одной записи.
+// Отработанные чанки возвращаются в free для повторного использования.
+type source struct {
+	ctx      context.Context
+	ch       <-chan []model.Name
+	free     chan<- []model.Name
+	chunk    []model.Name
+	pos      int
+	values   []any
+	err      error
+	progress *inserter.Progress
+}
+
+func newSource(ctx context.Context, ch <-chan []model.Name, free chan<- []model.Name, progress *inserter.Progress) *source {
+	return &source{
+		ctx:      ctx,
+		ch:       ch,
+		free:     free,
+		values:   make([]any, 0, len(inserter.Columns)),
+		progress: progress,
+	}
+}
+
+// Next implements pgx.CopyFromSource.
+func (s *source) Next() bool {
+	for s.pos >= len(s.chunk) {
+		s.release()
+		select {
+		case chunk, ok := <-s.ch:
+			if !ok {
+				s.values = s.values[:0]
+				return false
+			}
+			s.chunk, s.pos = chunk, 0
+		case <-s.ctx.Done():
+			s.err = s.ctx.Err()
+			return false
+		}
+	}
+	v := s.chunk[s.pos]
+	s.pos++
+	s.values = inserter.AppendValues(s.values[:0], v)
+	return true
+}
+
+// Values implements pgx.CopyFromSource.
+func (s *source) Values() ([]any, error) {
+	return s.values, nil
+}
+
+// Err implements pgx.CopyFromSource.
+func (s *source) Err() error {
+	return s.err
+}
+
+// release возвращает чанк в free и учитывает в progress отданные из него записи:
+// счетчик общий для воркеров, поэтому обновляется на чанк, а не на запись.
+func (s *source) release() {
+	if s.chunk == nil {
+		return
+	}
+	s.progress.AddRows(int64(s.pos))
+	select {
+	case s.free <- s.chunk:
+	default:
+	}
+	s.chunk = nil
+}
+
+var _ pgx.CopyFromSource = &source{}
+
+// Inserter распределяет записи между workers соединениями пула, каждое из которых
+// выполняет свой COPY.
+type Inserter struct {
+	inserter.Progress
+	pool       *pgxpool.Pool
+	workers    int
+	onConflict inserter.ConflictMode
+	counts     []int64
+	mu         sync.Mutex
+	stats      inserter.ConflictStats
+}
+
+func New(pool *pgxpool.Pool, workers int, onConflict inserter.ConflictMode) *Inserter {
+	return &Inserter{
+		pool:       pool,
+		workers:    max(workers, 1),
+		onConflict: onConflict,
+	}
+}
+
+// ConflictStats implements inserter.ConflictReporter.
+func (ins *Inserter) ConflictStats() inserter.ConflictStats {
+	ins.mu.Lock()
+	defer ins.mu.Unlock()
+	return ins.stats
+}
+
+func (ins *Inserter) addStats(n, inserted int64) {
+	ins.mu.Lock()
+	ins.stats.Add(ins.onConflict, n, inserted)
+	ins.mu.Unlock()
+}
+
+// Counts возвращает количество записей, вставленных каждым воркером в последнем вызове
+// Insert или InsertWithPipeline.
+func (ins *Inserter) Counts() []int64 {
+	return ins.counts
+}
+
+func (ins *Inserter) copyFrom(ctx context.Context, ch <-chan []model.Name, free chan<- []model.Name) (int64, error) {
+	conn, err := ins.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	src := newSource(ctx, ch, free, &ins.Progress)
+	defer src.release()
+
+	if ins.onConflict == inserter.ConflictError {
+		n, err := conn.CopyFrom(ctx, pgx.Identifier{"names"}, inserter.Columns, src)
+		if err != nil {
+			// COPY откатывается целиком: ничего из отданного воркеру не вставлено
+			return 0, err
+		}
+		ins.addStats(n, n)
+		return n, nil
+	}
+
+	// У каждого воркера своя сессия, а значит и своя временная таблица
+	if err := inserter.CreateStaging(ctx, conn.Conn()); err != nil {
+		return 0, err
+	}
+	defer inserter.DropStaging(ctx, conn.Conn())
+
+	n, err := conn.CopyFrom(ctx, pgx.Identifier{inserter.StagingTable}, inserter.Columns, src)
+	if err != nil {
+		return 0, err
+	}
+
+	inserted, err := inserter.MergeStaging(ctx, conn.Conn(), ins.onConflict)
+	if err != nil {
+		return 0, err
+	}
+
+	ins.addStats(n, inserted)
+	return n, nil
+}
+
+// insert читает names в текущей горутине и раздает чанки воркерам. queue задает
+// сколько готовых чанков может ожидать воркеров (0 — синхронная передача).
+func (ins *Inserter) insert(ctx context.Context, names iter.Seq[model.Name], queue int) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan []model.Name, queue)
+	free := make(chan []model.Name, ins.workers+queue+1)
+
+	counts := make([]int64, ins.workers)
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+	for w := range ins.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, e := ins.copyFrom(ctx, ch, free)
+			counts[w] = n
+			if e != nil {
+				// запоминаем первую ошибку и останавливаем остальных
+				once.Do(func() {
+					err = e
+					cancel()
+				})
+			}
+		}()
+	}
+
+	send := func(chunk []model.Name) bool {
+		select {
+		case ch <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	chunk := make([]model.Name, 0, chunkSize)
+	for v := range names {
+		chunk = append(chunk, v)
+		if len(chunk) >= chunkSize {
+			if !send(chunk) {
+				break
+			}
+			select {
+			case chunk = <-free:
+				chunk = chunk[:0]
+			default:
+				chunk = make([]model.Name, 0, chunkSize)
+			}
+		}
+	}
+
+	if len(chunk) > 0 && ctx.Err() == nil {
+		send(chunk)
+	}
+
+	close(ch)
+	wg.Wait()
+
+	ins.counts = counts
+
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+
+	return total, err
+}
+
+func (ins *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	return ins.insert(ctx, names, 0)
+}
+
+func (ins *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	return ins.insert(ctx, names, ins.workers)
+}
+
+var (
+	_ inserter.Inserter         = &Inserter{}
+	_ inserter.ConflictReporter = &Inserter{}
+	_ inserter.ProgressReporter = &Inserter{}
+)
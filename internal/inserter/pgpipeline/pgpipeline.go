@@ -0,0 +1,309 @@
+package pgpipeline
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+
+	"pg-bulk-flow/internal/inserter"
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const stmtName = "pipeline_insert_name"
+
+// Inserter вставляет записи по одной через prepared statement в режиме конвейера
+// расширенного протокола (PgConn.StartPipeline). Каждые batchSize записей завершаются
+// Sync — это граница неявной транзакции. Результаты читаются, только когда
+// неподтвержденных Sync становится maxSyncs, так что сеть не простаивает в ожидании
+// ответа сервера.
+type Inserter struct {
+	inserter.Progress
+	conn       *pgx.Conn
+	batchSize  int
+	maxSyncs   int
+	onConflict inserter.ConflictMode
+	stats      inserter.ConflictStats
+}
+
+func New(conn *pgx.Conn, batchSize, maxSyncs int, onConflict inserter.ConflictMode) *Inserter {
+	return &Inserter{
+		conn:       conn,
+		batchSize:  batchSize,
+		maxSyncs:   maxSyncs,
+		onConflict: onConflict,
+	}
+}
+
+// ConflictStats implements inserter.ConflictReporter.
+func (i *Inserter) ConflictStats() inserter.ConflictStats {
+	return i.stats
+}
+
+func (i *Inserter) prepare(ctx context.Context) (*pgconn.StatementDescription, error) {
+	sql := `INSERT INTO names (count, name_type, name_text, gender, fname, f_form, m_form, ethnic)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if i.onConflict != inserter.ConflictError {
+		sql = i.onConflict.UpsertValuesSQL()
+	}
+	return i.conn.Prepare(ctx, stmtName, sql)
+}
+
+// encoder кодирует параметры записи в формат протокола. Буфер переиспользуется:
+// SendQueryPrepared копирует параметры в буфер отправки сразу.
+type encoder struct {
+	m       *pgtype.Map
+	oids    []uint32
+	formats []int16
+	values  []any
+	params  [][]byte
+	buf     []byte
+}
+
+func newEncoder(m *pgtype.Map, sd *pgconn.StatementDescription) *encoder {
+	e := &encoder{
+		m:       m,
+		oids:    sd.ParamOIDs,
+		formats: make([]int16, len(sd.ParamOIDs)),
+		values:  make([]any, 0, len(sd.ParamOIDs)),
+		params:  make([][]byte, len(sd.ParamOIDs)),
+		buf:     make([]byte, 0, 256),
+	}
+	for j, oid := range e.oids {
+		e.formats[j] = m.FormatCodeForOID(oid)
+	}
+	return e
+}
+
+func (e *encoder) encode(v model.Name) error {
+	e.values = inserter.AppendValues(e.values[:0], v)
+	e.buf = e.buf[:0]
+	for j, val := range e.values {
+		start := len(e.buf)
+		buf, err := e.m.Encode(e.oids[j], e.formats[j], val, e.buf)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", inserter.Columns[j], err)
+		}
+		if buf == nil {
+			e.params[j] = nil // NULL
+			continue
+		}
+		e.buf = buf
+		e.params[j] = e.buf[start:len(e.buf):len(e.buf)]
+	}
+	return nil
+}
+
+// readSync читает результаты одного сегмента конвейера до PipelineSync включительно
+// и возвращает количество вставленных (не обновленных) записей.
+func (i *Inserter) readSync(p *pgconn.Pipeline) (int64, error) {
+	var (
+		inserted int64
+		firstErr error
+	)
+	for {
+		res, err := p.GetResults()
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) {
+				return 0, err
+			}
+			// После ошибки сервер пропускает команды до Sync, дочитываем сегмент
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		switch res := res.(type) {
+		case *pgconn.ResultReader:
+			if i.onConflict == inserter.ConflictError {
+				if _, err := res.Close(); err != nil {
+					return 0, err
+				}
+				inserted++
+				continue
+			}
+			r := res.Read()
+			if r.Err != nil {
+				firstErr = cmp.Or(firstErr, r.Err)
+				continue
+			}
+			if len(r.Rows) == 1 && len(r.Rows[0]) == 1 {
+				n, err := strconv.ParseInt(string(r.Rows[0][0]), 10, 64)
+				if err != nil {
+					return 0, err
+				}
+				inserted += n
+			}
+		case *pgconn.PipelineSync:
+			return inserted, firstErr
+		case nil:
+			return 0, errors.New("pipeline: unexpected end of results")
+		}
+	}
+}
+
+func (i *Inserter) insert(ctx context.Context, names iter.Seq[model.Name]) (count int64, err error) {
+	sd, err := i.prepare(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer i.conn.Deallocate(ctx, stmtName)
+
+	enc := newEncoder(i.conn.TypeMap(), sd)
+	p := i.conn.PgConn().StartPipeline(ctx)
+
+	var (
+		rows     int     // записей после последнего Sync
+		inflight []int64 // размеры отправленных, но не подтвержденных сегментов
+	)
+
+	sync := func() error {
+		if err := p.Sync(); err != nil {
+			return err
+		}
+		inflight = append(inflight, int64(rows))
+		rows = 0
+		return nil
+	}
+
+	// ack дожидается подтверждения самого старого сегмента
+	ack := func() error {
+		n := inflight[0]
+		inflight = inflight[1:]
+		inserted, err := i.readSync(p)
+		if err != nil {
+			return err
+		}
+		i.stats.Add(i.onConflict, n, inserted)
+		i.AddRows(n)
+		count += n
+		return nil
+	}
+
+	// drain синхронизирует остаток и дочитывает все сегменты. Отправленные сегменты
+	// сервер выполнит в любом случае (в том числе после ошибки в одном из них),
+	// поэтому они учитываются в count. Возвращает первую ошибку.
+	drain := func() error {
+		var first error
+		if rows > 0 {
+			if err := sync(); err != nil {
+				return err
+			}
+		}
+		for len(inflight) > 0 {
+			err := ack()
+			if err == nil {
+				continue
+			}
+			first = cmp.Or(first, err)
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) {
+				break // соединение потеряно
+			}
+		}
+		return first
+	}
+
+	defer func() {
+		if cerr := p.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	for v := range names {
+		if err := enc.encode(v); err != nil {
+			drain()
+			return count, err
+		}
+		p.SendQueryPrepared(stmtName, enc.params, enc.formats, nil)
+		rows++
+
+		if rows < i.batchSize {
+			continue
+		}
+		if err := sync(); err != nil {
+			return count, err
+		}
+		if len(inflight) >= i.maxSyncs {
+			if err := ack(); err != nil {
+				drain()
+				return count, err
+			}
+		}
+	}
+
+	return count, drain()
+}
+
+func (i *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	return i.insert(ctx, names)
+}
+
+// InsertWithPipeline дополнительно отвязывает чтение входа от отправки: записи
+// собираются в пакеты в отдельной горутине.
+func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	ch := make(chan []model.Name, 1)
+	free := make(chan []model.Name, 2)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	// горутина должна завершиться до возврата: вызывающий может продолжить работу
+	// с тем же итератором (например, Committer со следующей частью)
+	defer func() {
+		close(done)
+		<-exited
+	}()
+
+	go func() {
+		defer close(exited)
+		defer close(ch)
+		chunk := make([]model.Name, 0, i.batchSize)
+		for v := range names {
+			chunk = append(chunk, v)
+			if len(chunk) < i.batchSize {
+				continue
+			}
+			select {
+			case ch <- chunk:
+			case <-done:
+				return
+			}
+			select {
+			case chunk = <-free:
+				chunk = chunk[:0]
+			default:
+				chunk = make([]model.Name, 0, i.batchSize)
+			}
+		}
+		if len(chunk) > 0 {
+			select {
+			case ch <- chunk:
+			case <-done:
+			}
+		}
+	}()
+
+	return i.insert(ctx, func(yield func(model.Name) bool) {
+		for chunk := range ch {
+			for _, v := range chunk {
+				if !yield(v) {
+					return
+				}
+			}
+			select {
+			case free <- chunk:
+			default:
+			}
+		}
+	})
+}
+
+var (
+	_ inserter.Inserter         = &Inserter{}
+	_ inserter.ConflictReporter = &Inserter{}
+	_ inserter.ProgressReporter = &Inserter{}
+)
@@ -3,6 +3,7 @@ package pgxbatch
 import (
 	"context"
 	"iter"
+	"time"
 
 	"pg-bulk-flow/internal/inserter"
 	"pg-bulk-flow/internal/model"
@@ -10,21 +11,59 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+type insertBatch struct {
+	pgx.Batch
+	rows []model.Name // копии записей для повторной отправки частями (только bisect)
+}
+
+func (b *insertBatch) Add(v model.Name, keep bool) {
+	queue(&b.Batch, v)
+	if keep {
+		b.rows = append(b.rows, v)
+	}
+}
+
+func (b *insertBatch) Reset() {
+	clear(b.QueuedQueries)
+	b.QueuedQueries = b.QueuedQueries[:0]
+	clear(b.rows)
+	b.rows = b.rows[:0]
+}
+
+func queue(b *pgx.Batch, v model.Name) {
+	b.Queue("insert_name", v.Count, v.Type, v.Text, v.Gender, v.FName, v.FForm, v.MForm, v.Ethnic)
+}
+
 type Inserter struct {
-	conn      *pgx.Conn
-	batchSize int
+	inserter.BatchErrors
+	inserter.Progress
+	inserter.Timings
+	conn       *pgx.Conn
+	batchSize  int
+	onConflict inserter.ConflictMode
+	stats      inserter.ConflictStats
 }
 
-func New(conn *pgx.Conn, batchSize int) *Inserter {
+func New(conn *pgx.Conn, batchSize int, onConflict inserter.ConflictMode) *Inserter {
 	return &Inserter{
-		conn:      conn,
-		batchSize: batchSize,
+		conn:       conn,
+		batchSize:  batchSize,
+		onConflict: onConflict,
 	}
 }
 
+// ConflictStats implements inserter.ConflictReporter.
+func (i *Inserter) ConflictStats() inserter.ConflictStats {
+	return i.stats
+}
+
 func (i *Inserter) prepareInsert(ctx context.Context) error {
-	_, err := i.conn.Prepare(ctx, "insert_name",
-		`INSERT INTO names (count, name_type, name_text, gender) VALUES ($1, $2, $3, $4)`)
+	sql := `INSERT INTO names (count, name_type, name_text, gender, fname, f_form, m_form, ethnic)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if i.onConflict != inserter.ConflictError {
+		sql = i.onConflict.UpsertValuesSQL()
+	}
+	_, err := i.conn.Prepare(ctx, "insert_name", sql)
 	return err
 }
 
@@ -33,7 +72,55 @@ func (i *Inserter) deallocate(ctx context.Context) error {
 }
 
 func (i *Inserter) sendBatch(ctx context.Context, b *pgx.Batch) error {
-	return i.conn.SendBatch(ctx, b).Close()
+	n := int64(b.Len())
+	br := i.conn.SendBatch(ctx, b)
+
+	if i.onConflict == inserter.ConflictError {
+		if err := br.Close(); err != nil {
+			return err
+		}
+		i.stats.Add(i.onConflict, n, n)
+		i.AddRows(n)
+		return nil
+	}
+
+	var inserted int64
+	for range n {
+		var v int64
+		if err := br.QueryRow().Scan(&v); err != nil {
+			br.Close()
+			return err
+		}
+		inserted += v
+	}
+	if err := br.Close(); err != nil {
+		return err
+	}
+
+	i.stats.Add(i.onConflict, n, inserted)
+	i.AddRows(n)
+	return nil
+}
+
+// flush отправляет пакет и возвращает количество вставленных записей. Пакет
+// выполняется в неявной транзакции, поэтому отклоненный пакет можно повторить частями.
+func (i *Inserter) flush(ctx context.Context, b *insertBatch) (int64, error) {
+	// одно значение на пакет, вместе с повторами частей при BatchErrorBisect:
+	// иначе каждая часть попала бы в гистограмму как отдельный пакет
+	start := time.Now()
+	defer func() { i.RecordBatch(time.Since(start)) }()
+
+	err := i.sendBatch(ctx, &b.Batch)
+	if err == nil {
+		return int64(b.Len()), nil
+	}
+	return i.Handle(b.rows, err, func(rows []model.Name) error {
+		var sub pgx.Batch
+		for _, v := range rows {
+			queue(&sub, v)
+		}
+		return i.sendBatch(ctx, &sub)
+	})
 }
 
 func (i *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
@@ -43,28 +130,35 @@ func (i *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int6
 	defer i.deallocate(ctx)
 
 	var count int64
-	b := &pgx.Batch{}
-	reset := func(b *pgx.Batch) {
-		clear(b.QueuedQueries)
-		b.QueuedQueries = b.QueuedQueries[:0]
-	}
+	b := &insertBatch{}
+	keep := i.KeepRows()
 
+	fill := time.Now()
 	for v := range names {
-		b.Queue("insert_name", v.Count, v.Type, v.Text, v.Gender)
+		b.Add(v, keep)
 		if b.Len() >= i.batchSize {
-			if err := i.sendBatch(ctx, b); err != nil {
+			sent := time.Now()
+			i.AddScanWait(sent.Sub(fill))
+			n, err := i.flush(ctx, b)
+			fill = time.Now()
+			i.AddDBWait(fill.Sub(sent))
+			count += n
+			if err != nil {
 				return count, err
 			}
-			count += int64(b.Len())
-			reset(b)
+			b.Reset()
 		}
 	}
 
+	sent := time.Now()
+	i.AddScanWait(sent.Sub(fill))
 	if b.Len() > 0 {
-		if err := i.sendBatch(ctx, b); err != nil {
+		n, err := i.flush(ctx, b)
+		i.AddDBWait(time.Since(sent))
+		count += n
+		if err != nil {
 			return count, err
 		}
-		count += int64(b.Len())
 	}
 
 	return count, nil
@@ -76,14 +170,11 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 	}
 	defer i.deallocate(ctx)
 
-	ch := make(chan *pgx.Batch)
+	ch := make(chan *insertBatch)
 	done := make(chan struct{})
 
-	b1, b2 := &pgx.Batch{}, &pgx.Batch{}
-	reset := func(b *pgx.Batch) {
-		clear(b.QueuedQueries)
-		b.QueuedQueries = b.QueuedQueries[:0]
-	}
+	b1, b2 := &insertBatch{}, &insertBatch{}
+	keep := i.KeepRows()
 
 	var (
 		count int64
@@ -92,27 +183,36 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 
 	go func() {
 		defer close(done)
+		wait := time.Now()
 		for b := range ch {
-			if err := i.sendBatch(ctx, b); err != nil {
+			i.AddScanWait(time.Since(wait))
+			var n int64
+			n, err = i.flush(ctx, b)
+			count += n
+			if err != nil {
 				return
 			}
-			count += int64(b.Len())
+			wait = time.Now()
 		}
+		i.AddScanWait(time.Since(wait))
 	}()
 
 	for v := range names {
-		b1.Queue("insert_name", v.Count, v.Type, v.Text, v.Gender)
+		b1.Add(v, keep)
 		if b1.Len() >= i.batchSize {
+			blocked := time.Now()
 			select {
 			case ch <- b1:
+				i.AddDBWait(time.Since(blocked))
 				b1, b2 = b2, b1
-				reset(b1)
+				b1.Reset()
 			case <-done:
 				return count, err
 			}
 		}
 	}
 
+	blocked := time.Now()
 	if b1.Len() > 0 {
 		select {
 		case ch <- b1:
@@ -123,7 +223,14 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 
 	close(ch)
 	<-done
+	i.AddDBWait(time.Since(blocked))
 	return count, err
 }
 
-var _ inserter.Inserter = &Inserter{}
+var (
+	_ inserter.Inserter           = &Inserter{}
+	_ inserter.ConflictReporter   = &Inserter{}
+	_ inserter.DeadLetterReporter = &Inserter{}
+	_ inserter.ProgressReporter   = &Inserter{}
+	_ inserter.TimingsReporter    = &Inserter{}
+)
@@ -0,0 +1,31 @@
+package inserter
+
+import "sync/atomic"
+
+// Progress счетчик записей, переданных в базу. Инсертеры встраивают его и обновляют
+// из своих горутин, а читать его можно во время вставки, например для вывода
+// прогресса.
+//
+// Пакетные методы учитывают записи после ответа сервера, COPY — по мере отправки
+// в поток: до завершения команды они еще не видны в таблице. С -commit-every сюда
+// входят и записи незафиксированной транзакции.
+type Progress struct {
+	rows atomic.Int64
+}
+
+// AddRows учитывает n записей.
+func (p *Progress) AddRows(n int64) {
+	p.rows.Add(n)
+}
+
+// Rows implements ProgressReporter.
+func (p *Progress) Rows() int64 {
+	return p.rows.Load()
+}
+
+// ProgressReporter реализуют инсертеры, которые ведут Progress.
+type ProgressReporter interface {
+	Rows() int64
+}
+
+var _ ProgressReporter = &Progress{}
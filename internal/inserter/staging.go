@@ -0,0 +1,37 @@
+package inserter
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StagingTable временная таблица, в которую COPY-методы загружают данные в режимах
+// ON CONFLICT: сам COPY не умеет разрешать конфликты.
+const StagingTable = "names_staging"
+
+// CreateStaging создает (или очищает) временную таблицу в сессии conn.
+func CreateStaging(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TEMP TABLE IF NOT EXISTS `+StagingTable+` (
+		ord bigserial, count int, name_type name_type_enum, name_text text, gender gender_enum,
+		fname text, f_form text, m_form text, ethnic text[])`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, `TRUNCATE `+StagingTable)
+	return err
+}
+
+// DropStaging удаляет временную таблицу.
+func DropStaging(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `DROP TABLE IF EXISTS `+StagingTable)
+	return err
+}
+
+// MergeStaging переносит содержимое временной таблицы в names и возвращает количество
+// вставленных записей.
+func MergeStaging(ctx context.Context, conn *pgx.Conn, mode ConflictMode) (int64, error) {
+	var inserted int64
+	err := conn.QueryRow(ctx, mode.UpsertSQL(StagingTable)).Scan(&inserted)
+	return inserted, err
+}
@@ -0,0 +1,57 @@
+package inserter
+
+import (
+	"time"
+
+	"pg-bulk-flow/internal/latency"
+)
+
+// BatchTimings отчет о времени работы пакетного инсертера. Без -pipeline ожидания
+// сканера и базы в сумме дают почти все время вставки. С -pipeline они идут
+// параллельно: если обе величины заметно меньше общего времени, работа действительно
+// перекрывается, а если одна из них близка к нему, узкое место просто переехало.
+type BatchTimings struct {
+	Batches  latency.Summary `json:"batches"`         // длительность отправки каждого пакета
+	ScanWait float64         `json:"scanner_wait_ms"` // отправитель ждал, пока сканер наполнит пакет
+	DBWait   float64         `json:"db_wait_ms"`      // чтение входа ждало ответа базы
+}
+
+// TimingsReporter реализуют инсертеры, которые ведут Timings.
+type TimingsReporter interface {
+	BatchTimings() BatchTimings
+}
+
+// Timings собирает BatchTimings. Гистограмму и ожидание сканера обновляет горутина,
+// отправляющая пакеты, ожидание базы — горутина, читающая записи; читать можно
+// только после завершения вставки.
+type Timings struct {
+	batches  latency.Histogram
+	scanWait time.Duration
+	dbWait   time.Duration
+}
+
+// RecordBatch учитывает длительность отправки одного пакета.
+func (t *Timings) RecordBatch(d time.Duration) {
+	t.batches.Record(d)
+}
+
+// AddScanWait учитывает время ожидания записей от сканера.
+func (t *Timings) AddScanWait(d time.Duration) {
+	t.scanWait += d
+}
+
+// AddDBWait учитывает время ожидания базы.
+func (t *Timings) AddDBWait(d time.Duration) {
+	t.dbWait += d
+}
+
+// BatchTimings implements TimingsReporter.
+func (t *Timings) BatchTimings() BatchTimings {
+	return BatchTimings{
+		Batches:  t.batches.Summary(),
+		ScanWait: latency.Millis(t.scanWait),
+		DBWait:   latency.Millis(t.dbWait),
+	}
+}
+
+var _ TimingsReporter = &Timings{}
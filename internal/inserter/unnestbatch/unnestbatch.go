@@ -3,11 +3,13 @@ package unnestbatch
 import (
 	"context"
 	"iter"
+	"time"
 
 	"pg-bulk-flow/internal/inserter"
 	"pg-bulk-flow/internal/model"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type insertBatch struct {
@@ -15,6 +17,12 @@ type insertBatch struct {
 	Type   []model.NameType
 	Text   []string
 	Gender []model.Gender
+	FName  []string
+	FForm  []string
+	MForm  []string
+	Ethnic []pgtype.Text
+
+	rows []model.Name // копии записей для повторной отправки частями (только bisect)
 }
 
 func newInsertBatch(batchSize int) *insertBatch {
@@ -23,6 +31,10 @@ func newInsertBatch(batchSize int) *insertBatch {
 		Type:   make([]model.NameType, 0, batchSize),
 		Text:   make([]string, 0, batchSize),
 		Gender: make([]model.Gender, 0, batchSize),
+		FName:  make([]string, 0, batchSize),
+		FForm:  make([]string, 0, batchSize),
+		MForm:  make([]string, 0, batchSize),
+		Ethnic: make([]pgtype.Text, 0, batchSize),
 	}
 }
 
@@ -30,11 +42,18 @@ func (b *insertBatch) Len() int {
 	return len(b.Count)
 }
 
-func (b *insertBatch) Add(v model.Name) {
+func (b *insertBatch) Add(v model.Name, keep bool) {
+	if keep {
+		b.rows = append(b.rows, v)
+	}
 	b.Count = append(b.Count, v.Count)
 	b.Type = append(b.Type, v.Type)
 	b.Text = append(b.Text, v.Text)
 	b.Gender = append(b.Gender, v.Gender)
+	b.FName = append(b.FName, v.FName)
+	b.FForm = append(b.FForm, v.FForm)
+	b.MForm = append(b.MForm, v.MForm)
+	b.Ethnic = append(b.Ethnic, arrayLiteral(v.Ethnic))
 }
 
 func (b *insertBatch) Reset() {
@@ -42,31 +61,107 @@ func (b *insertBatch) Reset() {
 	b.Type = b.Type[:0]
 	b.Text = b.Text[:0]
 	b.Gender = b.Gender[:0]
+	b.FName = b.FName[:0]
+	b.FForm = b.FForm[:0]
+	b.MForm = b.MForm[:0]
+	b.Ethnic = b.Ethnic[:0]
+	clear(b.rows)
+	b.rows = b.rows[:0]
+}
+
+func (b *insertBatch) args() []any {
+	return []any{b.Count, b.Type, b.Text, b.Gender, b.FName, b.FForm, b.MForm, b.Ethnic}
+}
+
+// arrayLiteral кодирует массив в текстовое представление массива Postgres.
+// Передать text[][] нельзя: UNNEST разворачивает многомерный массив в плоский список,
+// поэтому массив каждой строки передается литералом и приводится к text[] на сервере.
+func arrayLiteral(ss []string) pgtype.Text {
+	if ss == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: string(inserter.AppendArrayLiteral(nil, ss)), Valid: true}
 }
 
 type Inserter struct {
-	conn      *pgx.Conn
-	batchSize int
+	inserter.BatchErrors
+	inserter.Progress
+	inserter.Timings
+	conn       *pgx.Conn
+	batchSize  int
+	onConflict inserter.ConflictMode
+	stats      inserter.ConflictStats
 }
 
-func New(conn *pgx.Conn, batchSize int) *Inserter {
+func New(conn *pgx.Conn, batchSize int, onConflict inserter.ConflictMode) *Inserter {
 	return &Inserter{
-		conn:      conn,
-		batchSize: batchSize,
+		conn:       conn,
+		batchSize:  batchSize,
+		onConflict: onConflict,
 	}
 }
 
+// ConflictStats implements inserter.ConflictReporter.
+func (i *Inserter) ConflictStats() inserter.ConflictStats {
+	return i.stats
+}
+
 func (i *Inserter) prepareInsert(ctx context.Context) error {
-	_, err := i.conn.Prepare(ctx, "insert_names",
-		`INSERT INTO names (count, name_type, name_text, gender)
-        SELECT UNNEST($1::int[]), UNNEST($2::name_type_enum[]), 
-               UNNEST($3::text[]), UNNEST($4::gender_enum[])`)
+	const from = `(SELECT count, name_type, name_text, gender, fname, f_form, m_form, ethnic::text[] AS ethnic, ord
+        FROM UNNEST($1::int[], $2::name_type_enum[], $3::text[], $4::gender_enum[],
+                    $5::text[], $6::text[], $7::text[], $8::text[])
+        WITH ORDINALITY AS t(count, name_type, name_text, gender, fname, f_form, m_form, ethnic, ord)) AS s`
+	sql := `INSERT INTO names (count, name_type, name_text, gender, fname, f_form, m_form, ethnic)
+        SELECT count, name_type, name_text, gender, fname, f_form, m_form, ethnic FROM ` + from
+	if i.onConflict != inserter.ConflictError {
+		sql = i.onConflict.UpsertSQL(from)
+	}
+	_, err := i.conn.Prepare(ctx, "insert_names", sql)
 	return err
 }
 
 func (i *Inserter) sendBatch(ctx context.Context, b *insertBatch) error {
-	_, err := i.conn.Exec(ctx, "insert_names", b.Count, b.Type, b.Text, b.Gender)
-	return err
+	n := int64(b.Len())
+
+	if i.onConflict == inserter.ConflictError {
+		if _, err := i.conn.Exec(ctx, "insert_names", b.args()...); err != nil {
+			return err
+		}
+		i.stats.Add(i.onConflict, n, n)
+		i.AddRows(n)
+		return nil
+	}
+
+	var inserted int64
+	err := i.conn.QueryRow(ctx, "insert_names", b.args()...).Scan(&inserted)
+	if err != nil {
+		return err
+	}
+
+	i.stats.Add(i.onConflict, n, inserted)
+	i.AddRows(n)
+	return nil
+}
+
+// flush отправляет пакет и возвращает количество вставленных записей. Пакет
+// вставляется одной командой, поэтому отклоненный пакет можно повторить частями.
+func (i *Inserter) flush(ctx context.Context, b *insertBatch) (int64, error) {
+	// одно значение на пакет, вместе с повторами частей при BatchErrorBisect:
+	// иначе каждая часть попала бы в гистограмму как отдельный пакет
+	start := time.Now()
+	defer func() { i.RecordBatch(time.Since(start)) }()
+
+	err := i.sendBatch(ctx, b)
+	if err == nil {
+		return int64(b.Len()), nil
+	}
+	return i.Handle(b.rows, err, func(rows []model.Name) error {
+		sub := newInsertBatch(len(rows))
+		for _, v := range rows {
+			sub.Add(v, false)
+		}
+		return i.sendBatch(ctx, sub)
+	})
 }
 
 func (i *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
@@ -77,23 +172,34 @@ func (i *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int6
 
 	var count int64
 	b := newInsertBatch(i.batchSize)
+	keep := i.KeepRows()
 
+	fill := time.Now()
 	for v := range names {
-		b.Add(v)
+		b.Add(v, keep)
 		if b.Len() >= i.batchSize {
-			if err := i.sendBatch(ctx, b); err != nil {
+			sent := time.Now()
+			i.AddScanWait(sent.Sub(fill))
+			n, err := i.flush(ctx, b)
+			fill = time.Now()
+			i.AddDBWait(fill.Sub(sent))
+			count += n
+			if err != nil {
 				return count, err
 			}
-			count += int64(b.Len())
 			b.Reset()
 		}
 	}
 
+	sent := time.Now()
+	i.AddScanWait(sent.Sub(fill))
 	if b.Len() > 0 {
-		if err := i.sendBatch(ctx, b); err != nil {
+		n, err := i.flush(ctx, b)
+		i.AddDBWait(time.Since(sent))
+		count += n
+		if err != nil {
 			return count, err
 		}
-		count += int64(b.Len())
 	}
 
 	return count, nil
@@ -109,6 +215,7 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 	done := make(chan struct{})
 	b1 := newInsertBatch(i.batchSize)
 	b2 := newInsertBatch(i.batchSize)
+	keep := i.KeepRows()
 
 	var (
 		count int64
@@ -117,19 +224,27 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 
 	go func() {
 		defer close(done)
+		wait := time.Now()
 		for b := range ch {
-			if err = i.sendBatch(ctx, b); err != nil {
+			i.AddScanWait(time.Since(wait))
+			var n int64
+			n, err = i.flush(ctx, b)
+			count += n
+			if err != nil {
 				return
 			}
-			count += int64(b.Len())
+			wait = time.Now()
 		}
+		i.AddScanWait(time.Since(wait))
 	}()
 
 	for v := range names {
-		b1.Add(v)
+		b1.Add(v, keep)
 		if b1.Len() >= i.batchSize {
+			blocked := time.Now()
 			select {
 			case ch <- b1:
+				i.AddDBWait(time.Since(blocked))
 				b1, b2 = b2, b1
 				b1.Reset()
 			case <-done:
@@ -138,6 +253,7 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 		}
 	}
 
+	blocked := time.Now()
 	if b1.Len() > 0 {
 		select {
 		case ch <- b1:
@@ -148,8 +264,15 @@ func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.
 
 	close(ch)
 	<-done
+	i.AddDBWait(time.Since(blocked))
 
 	return count, err
 }
 
-var _ inserter.Inserter = &Inserter{}
+var (
+	_ inserter.Inserter           = &Inserter{}
+	_ inserter.ConflictReporter   = &Inserter{}
+	_ inserter.DeadLetterReporter = &Inserter{}
+	_ inserter.ProgressReporter   = &Inserter{}
+	_ inserter.TimingsReporter    = &Inserter{}
+)
@@ -0,0 +1,25 @@
+package unnestbatch
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestArrayLiteral(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want pgtype.Text
+	}{
+		{nil, pgtype.Text{}},
+		{[]string{}, pgtype.Text{String: `{}`, Valid: true}},
+		{[]string{"slav"}, pgtype.Text{String: `{"slav"}`, Valid: true}},
+		{[]string{"a b", "NULL", ""}, pgtype.Text{String: `{"a b","NULL",""}`, Valid: true}},
+		{[]string{`q"x`, `b\s`}, pgtype.Text{String: `{"q\"x","b\\s"}`, Valid: true}},
+	}
+	for _, tt := range tests {
+		if got := arrayLiteral(tt.in); got != tt.want {
+			t.Errorf("arrayLiteral(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
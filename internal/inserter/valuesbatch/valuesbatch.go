@@ -0,0 +1,250 @@
+package valuesbatch
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+
+	"pg-bulk-flow/internal/inserter"
+	"pg-bulk-flow/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxParams максимальное количество параметров запроса в протоколе PostgreSQL
+// (количество передается в Bind как int16 без знака).
+const MaxParams = 65535
+
+// columnTypes типы колонок inserter.Columns. Нужны для VALUES внутри подзапроса
+// в режимах ON CONFLICT: там типы не выводятся из целевой таблицы.
+var columnTypes = []string{"int", "name_type_enum", "text", "gender_enum", "text", "text", "text", "text[]"}
+
+// MaxRows максимальный размер пакета, при котором запрос не превышает MaxParams.
+func MaxRows() int {
+	return MaxParams / len(inserter.Columns)
+}
+
+// insertBatch плоский список параметров пакета.
+type insertBatch struct {
+	args []any
+}
+
+func newInsertBatch(batchSize int) *insertBatch {
+	return &insertBatch{
+		args: make([]any, 0, batchSize*len(inserter.Columns)),
+	}
+}
+
+func (b *insertBatch) Len() int {
+	return len(b.args) / len(inserter.Columns)
+}
+
+func (b *insertBatch) Add(v model.Name) {
+	b.args = inserter.AppendValues(b.args, v)
+}
+
+func (b *insertBatch) Reset() {
+	clear(b.args)
+	b.args = b.args[:0]
+}
+
+// Inserter вставляет пакеты одним INSERT ... VALUES (...), (...). На каждый размер
+// пакета готовится свой prepared statement: полный пакет и, возможно, короткий
+// последний.
+type Inserter struct {
+	inserter.Progress
+	conn       *pgx.Conn
+	batchSize  int
+	onConflict inserter.ConflictMode
+	stmts      map[int]string // размер пакета -> имя prepared statement
+	stats      inserter.ConflictStats
+}
+
+// New создает Inserter. batchSize больше MaxRows уменьшается до MaxRows.
+func New(conn *pgx.Conn, batchSize int, onConflict inserter.ConflictMode) *Inserter {
+	return &Inserter{
+		conn:       conn,
+		batchSize:  min(batchSize, MaxRows()),
+		onConflict: onConflict,
+		stmts:      make(map[int]string),
+	}
+}
+
+// BatchSize возвращает фактический размер пакета с учетом MaxRows.
+func (i *Inserter) BatchSize() int {
+	return i.batchSize
+}
+
+// ConflictStats implements inserter.ConflictReporter.
+func (i *Inserter) ConflictStats() inserter.ConflictStats {
+	return i.stats
+}
+
+// insertSQL строит запрос на rows строк.
+func insertSQL(rows int, onConflict inserter.ConflictMode) string {
+	ncols := len(inserter.Columns)
+	upsert := onConflict != inserter.ConflictError
+
+	var b strings.Builder
+	b.Grow(rows * ncols * 8)
+	for r := range rows {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := range ncols {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(r*ncols + c + 1))
+			if upsert {
+				b.WriteString("::")
+				b.WriteString(columnTypes[c])
+			}
+		}
+		if upsert {
+			b.WriteString(", ")
+			b.WriteString(strconv.Itoa(r + 1)) // ord
+		}
+		b.WriteByte(')')
+	}
+
+	if !upsert {
+		return `INSERT INTO names (` + strings.Join(inserter.Columns, ", ") + `) VALUES ` + b.String()
+	}
+	from := `(VALUES ` + b.String() + `) AS t(` + strings.Join(inserter.Columns, ", ") + `, ord)`
+	return onConflict.UpsertSQL(from)
+}
+
+// stmt возвращает prepared statement для пакета из rows строк, подготавливая его
+// при первом обращении.
+func (i *Inserter) stmt(ctx context.Context, rows int) (string, error) {
+	if name, ok := i.stmts[rows]; ok {
+		return name, nil
+	}
+	name := fmt.Sprintf("insert_names_values_%d", rows)
+	if _, err := i.conn.Prepare(ctx, name, insertSQL(rows, i.onConflict)); err != nil {
+		return "", err
+	}
+	i.stmts[rows] = name
+	return name, nil
+}
+
+func (i *Inserter) deallocate(ctx context.Context) {
+	for rows, name := range i.stmts {
+		i.conn.Deallocate(ctx, name)
+		delete(i.stmts, rows)
+	}
+}
+
+func (i *Inserter) sendBatch(ctx context.Context, b *insertBatch) error {
+	n := b.Len()
+	name, err := i.stmt(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	if i.onConflict == inserter.ConflictError {
+		if _, err := i.conn.Exec(ctx, name, b.args...); err != nil {
+			return err
+		}
+		i.stats.Add(i.onConflict, int64(n), int64(n))
+		i.AddRows(int64(n))
+		return nil
+	}
+
+	var inserted int64
+	if err := i.conn.QueryRow(ctx, name, b.args...).Scan(&inserted); err != nil {
+		return err
+	}
+
+	i.stats.Add(i.onConflict, int64(n), inserted)
+	i.AddRows(int64(n))
+	return nil
+}
+
+func (i *Inserter) Insert(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	defer i.deallocate(ctx)
+
+	var count int64
+	b := newInsertBatch(i.batchSize)
+
+	for v := range names {
+		b.Add(v)
+		if b.Len() >= i.batchSize {
+			if err := i.sendBatch(ctx, b); err != nil {
+				return count, err
+			}
+			count += int64(b.Len())
+			b.Reset()
+		}
+	}
+
+	if b.Len() > 0 {
+		if err := i.sendBatch(ctx, b); err != nil {
+			return count, err
+		}
+		count += int64(b.Len())
+	}
+
+	return count, nil
+}
+
+func (i *Inserter) InsertWithPipeline(ctx context.Context, names iter.Seq[model.Name]) (int64, error) {
+	defer i.deallocate(ctx)
+
+	ch := make(chan *insertBatch)
+	done := make(chan struct{})
+	b1 := newInsertBatch(i.batchSize)
+	b2 := newInsertBatch(i.batchSize)
+
+	var (
+		count int64
+		err   error
+	)
+
+	go func() {
+		defer close(done)
+		for b := range ch {
+			if err = i.sendBatch(ctx, b); err != nil {
+				return
+			}
+			count += int64(b.Len())
+		}
+	}()
+
+	for v := range names {
+		b1.Add(v)
+		if b1.Len() >= i.batchSize {
+			select {
+			case ch <- b1:
+				b1, b2 = b2, b1
+				b1.Reset()
+			case <-done:
+				return count, err
+			}
+		}
+	}
+
+	if b1.Len() > 0 {
+		select {
+		case ch <- b1:
+		case <-done:
+			return count, err
+		}
+	}
+
+	close(ch)
+	<-done
+
+	return count, err
+}
+
+var (
+	_ inserter.Inserter         = &Inserter{}
+	_ inserter.ConflictReporter = &Inserter{}
+	_ inserter.ProgressReporter = &Inserter{}
+)
@@ -0,0 +1,46 @@
+package valuesbatch
+
+import (
+	"strings"
+	"testing"
+
+	"pg-bulk-flow/internal/inserter"
+)
+
+func TestInsertSQL(t *testing.T) {
+	got := insertSQL(2, inserter.ConflictError)
+	want := `INSERT INTO names (count, name_type, name_text, gender, fname, f_form, m_form, ethnic) VALUES ` +
+		`($1, $2, $3, $4, $5, $6, $7, $8), ($9, $10, $11, $12, $13, $14, $15, $16)`
+	if got != want {
+		t.Errorf("insertSQL = %s\nwant %s", got, want)
+	}
+
+	got = insertSQL(2, inserter.ConflictSkip)
+	for _, sub := range []string{
+		`($1::int, $2::name_type_enum, $3::text, $4::gender_enum, $5::text, $6::text, $7::text, $8::text[], 1)`,
+		`$16::text[], 2)) AS t(count, name_type, name_text, gender, fname, f_form, m_form, ethnic, ord)`,
+		`DO NOTHING`,
+	} {
+		if !strings.Contains(got, sub) {
+			t.Errorf("insertSQL = %s\nwant substring %s", got, sub)
+		}
+	}
+}
+
+func TestBatchSizeLimit(t *testing.T) {
+	tests := []struct {
+		batch, want int
+	}{
+		{1000, 1000},
+		{MaxRows(), MaxRows()},
+		{100000, MaxParams / len(inserter.Columns)},
+	}
+	for _, tt := range tests {
+		if got := New(nil, tt.batch, inserter.ConflictError).BatchSize(); got != tt.want {
+			t.Errorf("New(%d).BatchSize() = %d, want %d", tt.batch, got, tt.want)
+		}
+		if n := New(nil, tt.batch, inserter.ConflictError).BatchSize() * len(inserter.Columns); n > MaxParams {
+			t.Errorf("batch %d uses %d params", tt.batch, n)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package latency
+
+import (
+	"math/bits"
+	"time"
+)
+
+// subBits задает точность: каждый диапазон [2^e, 2^(e+1)) делится на 2^subBits
+// линейных корзин, поэтому относительная погрешность не больше 1/64.
+const (
+	subBits    = 6
+	subBuckets = 1 << subBits
+	numBuckets = subBuckets + (64-subBits)*subBuckets
+)
+
+// Histogram гистограмма длительностей в стиле HDR: логарифмические диапазоны по
+// степеням двойки, каждый разбит на линейные корзины. Память фиксирована и не зависит
+// от числа значений, запись не аллоцирует. Не потокобезопасна.
+type Histogram struct {
+	counts [numBuckets]int64
+	total  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// bucket номер корзины для значения v в наносекундах.
+func bucket(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	e := bits.Len64(v) - 1 // e >= subBits
+	shift := e - subBits
+	return subBuckets + shift*subBuckets + int(v>>shift) - subBuckets
+}
+
+// upper наибольшее значение, попадающее в корзину i.
+func upper(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+	shift := (i - subBuckets) / subBuckets
+	m := uint64((i-subBuckets)%subBuckets + subBuckets)
+	return (m+1)<<shift - 1
+}
+
+// Record учитывает длительность d. Отрицательные значения считаются нулем.
+func (h *Histogram) Record(d time.Duration) {
+	d = max(d, 0)
+	h.counts[bucket(uint64(d))]++
+	if h.total == 0 || d < h.min {
+		h.min = d
+	}
+	h.max = max(h.max, d)
+	h.total++
+	h.sum += d
+}
+
+// Count возвращает количество значений.
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+// Max возвращает наибольшее значение.
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Quantile возвращает значение, не меньше которого q процентов значений (0 < q <= 100),
+// с точностью до корзины. Как и в HDR, возвращается верхняя граница корзины, но не
+// больше максимума.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := int64(q / 100 * float64(h.total))
+	if float64(target) < q/100*float64(h.total) {
+		target++ // округление вверх
+	}
+	target = max(target, 1)
+
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			return min(time.Duration(upper(i)), h.max)
+		}
+	}
+	return h.max
+}
+
+// Summary сводка гистограммы для отчета, в миллисекундах.
+type Summary struct {
+	Count int64   `json:"count"`
+	Min   float64 `json:"min_ms"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// Summary считает сводку.
+func (h *Histogram) Summary() Summary {
+	if h.total == 0 {
+		return Summary{}
+	}
+	return Summary{
+		Count: h.total,
+		Min:   Millis(h.min),
+		Mean:  Millis(h.sum / time.Duration(h.total)),
+		P50:   Millis(h.Quantile(50)),
+		P90:   Millis(h.Quantile(90)),
+		P99:   Millis(h.Quantile(99)),
+		Max:   Millis(h.max),
+	}
+}
+
+// Millis переводит d в дробные миллисекунды с точностью до микросекунды.
+func Millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
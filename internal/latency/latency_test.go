@@ -0,0 +1,78 @@
+package latency
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	for _, v := range []uint64{0, 1, 63, 64, 65, 127, 128, 129, 1000, 1 << 20, 1<<40 + 12345, 1<<63 + 1} {
+		i := bucket(v)
+		if i < 0 || i >= numBuckets {
+			t.Fatalf("bucket(%d) = %d out of range", v, i)
+		}
+		if up := upper(i); v > up {
+			t.Errorf("bucket(%d) = %d with upper bound %d", v, i, up)
+		}
+		if i > 0 && v <= upper(i-1) {
+			t.Errorf("bucket(%d) = %d, value fits previous bucket", v, i)
+		}
+		// погрешность верхней границы не больше ширины корзины
+		if up := upper(i); v >= subBuckets && float64(up-v) > float64(v)/subBuckets {
+			t.Errorf("bucket(%d) upper bound %d too far", v, up)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	var h Histogram
+	if got := h.Quantile(50); got != 0 {
+		t.Errorf("empty Quantile = %v, want 0", got)
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	values := make([]time.Duration, 10000)
+	for i := range values {
+		values[i] = time.Duration(r.ExpFloat64() * float64(time.Millisecond))
+		h.Record(values[i])
+	}
+	slices.Sort(values)
+
+	for _, q := range []float64{50, 90, 99, 100} {
+		want := values[int(q/100*float64(len(values)))-1]
+		got := h.Quantile(q)
+		if got < want || float64(got-want) > float64(want)/subBuckets {
+			t.Errorf("Quantile(%v) = %v, want %v within 1/%d", q, got, want, subBuckets)
+		}
+	}
+
+	if h.Count() != int64(len(values)) || h.Max() != values[len(values)-1] {
+		t.Errorf("Count = %d, Max = %v", h.Count(), h.Max())
+	}
+}
+
+func TestSummary(t *testing.T) {
+	var h Histogram
+	for _, d := range []time.Duration{2 * time.Millisecond, 4 * time.Millisecond, 6 * time.Millisecond} {
+		h.Record(d)
+	}
+	s := h.Summary()
+	if s.Count != 3 || s.Min != 2 || s.Mean != 4 || s.Max != 6 {
+		t.Errorf("Summary = %+v", s)
+	}
+	if s.P50 < 4 || s.P50 > 4*(1+1.0/subBuckets) {
+		t.Errorf("P50 = %v, want about 4", s.P50)
+	}
+}
+
+func TestRecordAllocs(t *testing.T) {
+	var h Histogram
+	allocs := testing.AllocsPerRun(1000, func() {
+		h.Record(time.Millisecond)
+	})
+	if allocs != 0 {
+		t.Errorf("Record allocs = %v, want 0", allocs)
+	}
+}
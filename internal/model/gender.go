@@ -51,7 +51,7 @@ func (g *Gender) ScanText(v pgtype.Text) error {
 
 // MarshalJSON implements json.Marshaler.
 func (g Gender) MarshalJSON() ([]byte, error) {
-	return []byte(g.String()), nil
+	return strconv.AppendQuote(nil, g.String()), nil
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -89,7 +89,7 @@ func DetectGender(name string, nameType NameType) Gender {
 	switch nameType {
 	case NameTypeSurname:
 		if strings.HasSuffix(name, "ов") ||
-			strings.HasSuffix(name, "eв") ||
+			strings.HasSuffix(name, "ев") ||
 			strings.HasSuffix(name, "ёв") ||
 			strings.HasSuffix(name, "ин") ||
 			strings.HasSuffix(name, "ын") ||
@@ -100,7 +100,7 @@ func DetectGender(name string, nameType NameType) Gender {
 			return GenderMale
 		}
 		if strings.HasSuffix(name, "ова") ||
-			strings.HasSuffix(name, "eва") ||
+			strings.HasSuffix(name, "ева") ||
 			strings.HasSuffix(name, "ёва") ||
 			strings.HasSuffix(name, "вна") ||
 			strings.HasSuffix(name, "ина") ||
@@ -121,7 +121,7 @@ func DetectGender(name string, nameType NameType) Gender {
 			strings.HasSuffix(name, "евна") ||
 			strings.HasSuffix(name, "ична") ||
 			strings.HasSuffix(name, "инична") {
-			return GenderMale
+			return GenderFemale
 		}
 	}
 	return GenderUnknown
@@ -0,0 +1,50 @@
+package model
+
+import "testing"
+
+func TestDetectGender(t *testing.T) {
+	tests := []struct {
+		name     string
+		nameType NameType
+		want     Gender
+	}{
+		{"Иванов", NameTypeSurname, GenderMale},
+		{"Иванова", NameTypeSurname, GenderFemale},
+		{"Сергеев", NameTypeSurname, GenderMale},
+		{"Сергеева", NameTypeSurname, GenderFemale},
+		{"Королёв", NameTypeSurname, GenderMale},
+		{"Королёва", NameTypeSurname, GenderFemale},
+		{"Пушкин", NameTypeSurname, GenderMale},
+		{"Пушкина", NameTypeSurname, GenderFemale},
+		{"Птицын", NameTypeSurname, GenderMale},
+		{"Птицына", NameTypeSurname, GenderFemale},
+		{"Толстой", NameTypeSurname, GenderMale},
+		{"Толстая", NameTypeSurname, GenderFemale},
+		{"Достоевский", NameTypeSurname, GenderMale},
+		{"Крайняя", NameTypeSurname, GenderFemale},
+		{"Белый", NameTypeSurname, GenderMale},
+		{"  ИВАНОВ ", NameTypeSurname, GenderMale},
+		{"Шевчук", NameTypeSurname, GenderUnknown},
+		{"Smith", NameTypeSurname, GenderUnknown},
+
+		{"Иванович", NameTypePatronymic, GenderMale},
+		{"Сергеевич", NameTypePatronymic, GenderMale},
+		{"Ильич", NameTypePatronymic, GenderMale},
+		{"Ивановна", NameTypePatronymic, GenderFemale},
+		{"Сергеевна", NameTypePatronymic, GenderFemale},
+		{"Ильинична", NameTypePatronymic, GenderFemale},
+		{"Никитична", NameTypePatronymic, GenderFemale},
+		{"Оглы", NameTypePatronymic, GenderUnknown},
+
+		{"Иван", NameTypeName, GenderUnknown},
+		{"Мария", NameTypeName, GenderUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nameType.String()+"/"+tt.name, func(t *testing.T) {
+			if got := DetectGender(tt.name, tt.nameType); got != tt.want {
+				t.Errorf("DetectGender(%q, %s) = %s, want %s", tt.name, tt.nameType, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 type Name struct {
@@ -12,15 +16,22 @@ type Name struct {
 	Text   string   `json:"text" db:"name_text"`
 	Type   NameType `json:"type" db:"name_type"`
 	Gender Gender   `json:"gender" db:"gender"`
+	FName  string   `json:"fname,omitempty" db:"fname"`
+	FForm  string   `json:"f_form,omitempty" db:"f_form"` // женская форма
+	MForm  string   `json:"m_form,omitempty" db:"m_form"` // мужская форма
+	Ethnic []string `json:"ethnic,omitempty" db:"ethnic"` // nil — нет данных
+	Line   int      `json:"-" db:"-"`                     // номер строки во входном файле
 }
 
 func (n Name) Validate() error {
 	var errs []error
 	if !n.Type.IsValid() {
-		errs = append(errs, fmt.Errorf("name_type must be enums %q, got %q", AllGenders, n.Type))
+		errs = append(errs, &ValidationError{ReasonNameType,
+			fmt.Errorf("name_type must be enums %q, got %q", AllNameTypes, n.Type)})
 	}
 	if !n.Gender.IsValid() {
-		errs = append(errs, fmt.Errorf("gender must be enums %q, got %q", AllNameTypes, n.Gender))
+		errs = append(errs, &ValidationError{ReasonGender,
+			fmt.Errorf("gender must be enums %q, got %q", AllGenders, n.Gender)})
 	}
 	if err := ValidateName(n.Text); err != nil {
 		errs = append(errs, fmt.Errorf("name_text: %w", err))
@@ -28,13 +39,211 @@ func (n Name) Validate() error {
 	return errors.Join(errs...)
 }
 
-// NormalizeName. Всегда копирует строку.
+// Reason причина, по которой запись не прошла валидацию.
+type Reason string
+
+const (
+	ReasonNameType    Reason = "name_type"
+	ReasonGender      Reason = "gender"
+	ReasonEmpty       Reason = "empty"
+	ReasonTooLong     Reason = "too_long"
+	ReasonInvalidChar Reason = "invalid_char"
+	ReasonFormat      Reason = "format"       // разделители в начале, в конце или подряд
+	ReasonScript      Reason = "script"       // буквы не из разрешенной письменности
+	ReasonMixedScript Reason = "mixed_script" // кириллица и латиница в одном слове
+)
+
+// ValidationError ошибка валидации с типизированной причиной.
+type ValidationError struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Script допустимая письменность имен.
+//
+//go:generate stringer -type Script -linecomment -output script_string.go
+type Script int8
+
+const (
+	ScriptMixed    Script = iota // mixed
+	ScriptCyrillic               // cyrillic
+	ScriptLatin                  // latin
+)
+
+var AllScripts = []Script{ScriptMixed, ScriptCyrillic, ScriptLatin}
+
+func ParseScript(s string) (Script, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "mixed", "any":
+		return ScriptMixed, nil
+	case "cyrillic", "cyr":
+		return ScriptCyrillic, nil
+	case "latin", "lat":
+		return ScriptLatin, nil
+	}
+	return 0, fmt.Errorf("unknown script %q", s)
+}
+
+// NameRules правила нормализации и валидации имен.
+type NameRules struct {
+	MaxLength  int    // максимальная длина в символах
+	Script     Script // ScriptMixed допускает обе письменности, но не в одном слове
+	Capitalize bool   // NormalizeName делает заглавной первую букву каждой части
+}
+
+var DefaultNameRules = NameRules{
+	MaxLength: 64,
+	Script:    ScriptMixed,
+}
+
+var nameRules = DefaultNameRules
+
+// SetNameRules задает правила для NormalizeName и ValidateName. Вызывайте до начала обработки:
+// правила читаются без синхронизации.
+func SetNameRules(r NameRules) {
+	nameRules = r
+}
+
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+// NormalizeName приводит имя к каноническому виду: NFC, единые дефис и апостроф,
+// одиночные пробелы между словами (и без пробелов вокруг дефиса). С NameRules.Capitalize
+// первая буква каждой части становится заглавной ("иванов-петров" -> "Иванов-Петров").
+// Регистр остальных букв сохраняется: внутренние заглавные значимы (ЦыренДоржи), а
+// приведение регистра сводило бы разные записи источника к одному ключу (иван, Иван).
+// Всегда копирует строку.
 func NormalizeName(s string) (string, error) {
-	// TODO
-	return strings.Clone(strings.TrimSpace(s)), nil
+	if !utf8.ValidString(s) {
+		return "", ErrInvalidUTF8
+	}
+
+	s = norm.NFC.String(s)
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	var (
+		space   bool // отложенный пробел
+		upper   = true
+		partLen int // длина текущей части в символах
+	)
+	for _, r := range s {
+		r = normalizeRune(r)
+
+		switch {
+		case r == ' ':
+			space = b.Len() > 0
+			continue
+		case r == '-' || r == '\'':
+			space = false
+			b.WriteRune(r)
+			// после апострофа заглавная только за однобуквенной приставкой: O'Brien, D'Artagnan
+			upper = r == '-' || partLen == 1
+			partLen = 0
+			continue
+		}
+
+		if space {
+			if last, _ := utf8.DecodeLastRuneInString(b.String()); last != '-' && last != '\'' {
+				b.WriteByte(' ')
+				upper = true
+				partLen = 0
+			}
+			space = false
+		}
+
+		if !unicode.IsLetter(r) {
+			b.WriteRune(r) // мусор оставляем валидатору
+			continue
+		}
+
+		if upper && nameRules.Capitalize {
+			r = unicode.ToUpper(r)
+		}
+		b.WriteRune(r)
+		upper = false
+		partLen++
+	}
+
+	return b.String(), nil
+}
+
+func normalizeRune(r rune) rune {
+	switch r {
+	case '‐', '‑', '‒', '–', '—', '―', '−', '﹣', '－':
+		return '-'
+	case '‘', '’', 'ʼ', 'ʹ', '`', '´', '＇':
+		return '\''
+	}
+	if unicode.IsSpace(r) {
+		return ' '
+	}
+	return r
 }
 
+// ValidateName проверяет нормализованное имя (см. NormalizeName) по текущим правилам
+// (см. SetNameRules). Ошибка всегда *ValidationError.
 func ValidateName(s string) error {
-	// TODO
+	rules := nameRules
+
+	if s == "" {
+		return &ValidationError{ReasonEmpty, errors.New("empty name")}
+	}
+	if n := utf8.RuneCountInString(s); rules.MaxLength > 0 && n > rules.MaxLength {
+		return &ValidationError{ReasonTooLong, fmt.Errorf("name too long: %d > %d", n, rules.MaxLength)}
+	}
+
+	var (
+		prevSep = true  // предыдущий символ — разделитель (или начало строки)
+		cyr     = false // в текущем слове есть кириллица
+		lat     = false // в текущем слове есть латиница
+	)
+	for _, r := range s {
+		switch {
+		case r == ' ' || r == '-' || r == '\'':
+			if prevSep {
+				return &ValidationError{ReasonFormat, fmt.Errorf("unexpected %q", r)}
+			}
+			prevSep = true
+			if r != '\'' {
+				cyr, lat = false, false
+			}
+			continue
+
+		case unicode.Is(unicode.Cyrillic, r):
+			if rules.Script == ScriptLatin {
+				return &ValidationError{ReasonScript, fmt.Errorf("cyrillic letter %q not allowed", r)}
+			}
+			cyr = true
+
+		case unicode.Is(unicode.Latin, r):
+			if rules.Script == ScriptCyrillic {
+				return &ValidationError{ReasonScript, fmt.Errorf("latin letter %q not allowed", r)}
+			}
+			lat = true
+
+		default:
+			return &ValidationError{ReasonInvalidChar, fmt.Errorf("invalid character %q", r)}
+		}
+
+		if cyr && lat {
+			return &ValidationError{ReasonMixedScript, errors.New("cyrillic and latin letters in one word")}
+		}
+		prevSep = false
+	}
+
+	if prevSep {
+		return &ValidationError{ReasonFormat, errors.New("trailing separator")}
+	}
+
 	return nil
 }
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		input      string
+		capitalize bool
+		want       string
+	}{
+		{"иванов", true, "Иванов"},
+		{"  ИВАНОВ  ", true, "ИВАНОВ"},
+		{"иванов-петров", true, "Иванов-Петров"},
+		{"Иванов – Петров", true, "Иванов-Петров"},
+		{"Иванов—петров", true, "Иванов-Петров"},
+		{"ЖАН-МАРТИН   ЧАРЛЬЗ", true, "ЖАН-МАРТИН ЧАРЛЬЗ"},
+		{"якдехи \t самила", true, "Якдехи Самила"},
+		{"цыренДоржи", true, "ЦыренДоржи"},
+		{"o’brien", true, "O'Brien"},
+		{"d`artagnan", true, "D'Artagnan"},
+		{"солов'ян", true, "Солов'ян"},
+		{"е\u0308ж", true, "Ёж"},     // NFC: е + combining diaeresis -> ё
+		{"и\u0306ван", true, "Йван"}, // NFC: и + combining breve -> й
+		{"", true, ""},
+		// без Capitalize регистр не меняется: иначе "иван" и "Иван" дали бы один ключ
+		{"иванов", false, "иванов"},
+		{"иванов – петров", false, "иванов-петров"},
+		{"o’brien", false, "o'brien"},
+	}
+
+	defer SetNameRules(DefaultNameRules)
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rules := DefaultNameRules
+			rules.Capitalize = tt.capitalize
+			SetNameRules(rules)
+
+			got, err := NormalizeName(tt.input)
+			if err != nil {
+				t.Fatalf("NormalizeName error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NormalizeName("\xff"); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("NormalizeName(invalid utf8) error = %v, want %v", err, ErrInvalidUTF8)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		rules  NameRules
+		reason Reason // пусто — имя валидно
+	}{
+		{"Cyrillic", "Иванов", DefaultNameRules, ""},
+		{"Latin", "Smith", DefaultNameRules, ""},
+		{"Double", "Иванов-Smith", DefaultNameRules, ""},
+		{"Apostrophe", "O'Brien", DefaultNameRules, ""},
+		{"Empty", "", DefaultNameRules, ReasonEmpty},
+		{"Too long", "Аааааааааа", NameRules{MaxLength: 5}, ReasonTooLong},
+		{"Digits", "Иванов2", DefaultNameRules, ReasonInvalidChar},
+		{"Parens", "Стефано (Stefano)", DefaultNameRules, ReasonInvalidChar},
+		{"Leading hyphen", "-Иванов", DefaultNameRules, ReasonFormat},
+		{"Double hyphen", "Иванов--Петров", DefaultNameRules, ReasonFormat},
+		{"Trailing hyphen", "Иванов-", DefaultNameRules, ReasonFormat},
+		{"Homoglyph", "Cергей", DefaultNameRules, ReasonMixedScript},
+		{"Latin in cyrillic", "Smith", NameRules{Script: ScriptCyrillic}, ReasonScript},
+		{"Cyrillic in latin", "Иванов", NameRules{Script: ScriptLatin}, ReasonScript},
+		{"Cyrillic only", "Иванов-Петров", NameRules{Script: ScriptCyrillic}, ""},
+	}
+
+	defer SetNameRules(DefaultNameRules)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetNameRules(tt.rules)
+			err := ValidateName(tt.input)
+
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("ValidateName error = %v", err)
+				}
+				return
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("ValidateName error = %v, want reason %s", err, tt.reason)
+			}
+			if ve.Reason != tt.reason {
+				t.Errorf("reason = %s, want %s (%v)", ve.Reason, tt.reason, err)
+			}
+		})
+	}
+}
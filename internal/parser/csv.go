@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"pg-bulk-flow/internal/model"
+	"pg-bulk-flow/internal/scanner"
+)
+
+var (
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+	ErrBareQuote         = errors.New("extraneous data after quoted field")
+	ErrMissingField      = errors.New("missing field")
+)
+
+const noColumn = -1
+
+// CSVFormat описывает формат CSV/TSV: разделитель, символ кавычек и номера колонок.
+// Формат общий для всех CSVParser и после разрешения заголовка только читается.
+//
+// Записи должны умещаться в одну строку: сканер построчный, поэтому переводы строк
+// внутри кавычек не поддерживаются.
+type CSVFormat struct {
+	Comma byte
+	Quote byte // 0 — кавычки не используются
+
+	text, count, gender int      // номера колонок с нуля или noColumn
+	names               []string // имена колонок, ожидающие заголовка (по порядку text, count, gender)
+}
+
+// NewCSVFormat создает формат по описанию колонок вида "text=1,count=3,gender=2".
+// Значение — номер колонки с единицы или имя колонки из заголовка. Колонка text
+// обязательна, без count счетчик равен 1, без gender пол неизвестен.
+func NewCSVFormat(comma, quote byte, columns string) (*CSVFormat, error) {
+	if comma == quote || comma == '\n' || comma == '\r' {
+		return nil, fmt.Errorf("invalid delimiter %q", comma)
+	}
+
+	f := &CSVFormat{
+		Comma:  comma,
+		Quote:  quote,
+		text:   noColumn,
+		count:  noColumn,
+		gender: noColumn,
+		names:  make([]string, 3),
+	}
+
+	for item := range strings.SplitSeq(columns, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid column mapping %q", item)
+		}
+
+		var idx int
+		switch strings.ToLower(key) {
+		case "text":
+			idx = 0
+		case "count":
+			idx = 1
+		case "gender":
+			idx = 2
+		default:
+			return nil, fmt.Errorf("unknown column %q", key)
+		}
+
+		if n, err := strconv.Atoi(value); err == nil {
+			if n <= 0 {
+				return nil, fmt.Errorf("column %s: number must be positive", key)
+			}
+			*f.column(idx) = n - 1
+		} else {
+			f.names[idx] = value
+		}
+	}
+
+	if f.text == noColumn && f.names[0] == "" {
+		return nil, errors.New("text column is required")
+	}
+
+	return f, nil
+}
+
+func (f *CSVFormat) column(idx int) *int {
+	return [...]*int{&f.text, &f.count, &f.gender}[idx]
+}
+
+// NeedsHeader сообщает, что часть колонок задана именами и нужен заголовок.
+func (f *CSVFormat) NeedsHeader() bool {
+	for _, name := range f.names {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHeader разрешает имена колонок по строке заголовка.
+func (f *CSVFormat) ParseHeader(line []byte) error {
+	var rec csvRecord
+	if err := rec.split(line, f.Comma, f.Quote); err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+
+	for idx, name := range f.names {
+		if name == "" {
+			continue
+		}
+		i := indexFold(&rec, name)
+		if i < 0 {
+			return fmt.Errorf("header: column %q not found", name)
+		}
+		*f.column(idx) = i
+		f.names[idx] = ""
+	}
+
+	return nil
+}
+
+func indexFold(rec *csvRecord, name string) int {
+	for i := range rec.len() {
+		if strings.EqualFold(string(bytes.TrimSpace(rec.field(i))), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AppendHeader дописывает к dst строку заголовка, в которой колонки, заданные именами,
+// занимают первые позиции, не занятые колонками с номерами. Вместе с AppendRecord
+// позволяет получить данные в этом формате, например, для оценки стоимости разбора.
+func (f *CSVFormat) AppendHeader(dst []byte) []byte {
+	var fields []string
+	set := func(i int, s string) {
+		for len(fields) <= i {
+			fields = append(fields, "")
+		}
+		fields[i] = s
+	}
+	for _, i := range []int{f.text, f.count, f.gender} {
+		if i != noColumn {
+			set(i, "")
+		}
+	}
+
+	pos := 0
+	for _, name := range f.names {
+		if name == "" {
+			continue
+		}
+		for pos == f.text || pos == f.count || pos == f.gender || pos < len(fields) && fields[pos] != "" {
+			pos++
+		}
+		set(pos, name)
+	}
+
+	for i, s := range fields {
+		if i > 0 {
+			dst = append(dst, f.Comma)
+		}
+		dst = f.appendField(dst, s)
+	}
+	return append(dst, '\n')
+}
+
+// AppendRecord дописывает к dst строку с полями в колонках формата. Колонки, заданные
+// именами, должны быть уже разрешены ParseHeader. Без кавычек значения не должны
+// содержать разделитель.
+func (f *CSVFormat) AppendRecord(dst []byte, text, count, gender string) []byte {
+	width := max(f.text, f.count, f.gender) + 1
+	for i := range width {
+		if i > 0 {
+			dst = append(dst, f.Comma)
+		}
+		switch i {
+		case f.text:
+			dst = f.appendField(dst, text)
+		case f.count:
+			dst = f.appendField(dst, count)
+		case f.gender:
+			dst = f.appendField(dst, gender)
+		}
+	}
+	return append(dst, '\n')
+}
+
+func (f *CSVFormat) appendField(dst []byte, s string) []byte {
+	if f.Quote == 0 || strings.IndexByte(s, f.Comma) < 0 && strings.IndexByte(s, f.Quote) < 0 {
+		return append(dst, s...)
+	}
+	dst = append(dst, f.Quote)
+	for i := 0; i < len(s); i++ {
+		if s[i] == f.Quote {
+			dst = append(dst, f.Quote)
+		}
+		dst = append(dst, s[i])
+	}
+	return append(dst, f.Quote)
+}
+
+// CSVParser парсит строку CSV/TSV в model.Name.
+// Парсер НЕ потокобезопасен. Создавайте новый для каждой горутины.
+type CSVParser struct {
+	format *CSVFormat
+	rec    csvRecord
+	stats  Stats
+}
+
+func NewCSVParser(format *CSVFormat) *CSVParser {
+	return &CSVParser{format: format}
+}
+
+func (p *CSVParser) Stats() Stats {
+	return p.stats
+}
+
+func (p *CSVParser) field(i int) ([]byte, bool) {
+	if i == noColumn {
+		return nil, true
+	}
+	if i >= p.rec.len() {
+		return nil, false
+	}
+	return p.rec.field(i), true
+}
+
+// Parse implements scanner.Parser. Синтаксические ошибки учитываются в Stats.InvalidCSV.
+func (p *CSVParser) Parse(ctx context.Context, data []byte) (model.Name, error) {
+	if err := p.rec.split(data, p.format.Comma, p.format.Quote); err != nil {
+		p.stats.InvalidCSV++
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageCSV, Err: err}
+	}
+
+	text, ok1 := p.field(p.format.text)
+	count, ok2 := p.field(p.format.count)
+	gender, ok3 := p.field(p.format.gender)
+	if !(ok1 && ok2 && ok3) {
+		p.stats.InvalidCSV++
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageCSV,
+			Err: fmt.Errorf("%w: got %d fields", ErrMissingField, p.rec.len())}
+	}
+
+	if len(bytes.TrimSpace(text)) == 0 {
+		p.stats.EmptyFields++
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageEmptyFields, Err: errors.New("empty text")}
+	}
+
+	name, err := model.NormalizeName(string(text))
+	if err != nil {
+		p.stats.InvalidName++
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageName, Err: fmt.Errorf("invalid text: %w", err)}
+	}
+
+	g, err := model.ParseGender(string(gender))
+	if err != nil {
+		p.stats.InvalidGender++
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageGender, Err: fmt.Errorf("invalid gender: %w", err)}
+	}
+
+	n := int64(1)
+	if s := bytes.TrimSpace(count); len(s) > 0 {
+		n, err = strconv.ParseInt(string(s), 10, 64)
+		if err != nil {
+			n = 0
+		}
+	}
+	if !(0 < n && n <= math.MaxInt32) {
+		p.stats.InvalidCount++
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageCount, Err: fmt.Errorf("count must be [1..%d]", math.MaxInt32)}
+	}
+
+	return model.Name{
+		Text:   name,
+		Gender: g,
+		Count:  int32(n),
+	}, nil
+}
+
+// csvRecord поля разобранной строки. Содержимое полей хранится подряд в buf.
+type csvRecord struct {
+	buf  []byte
+	ends []int
+}
+
+func (r *csvRecord) len() int {
+	return len(r.ends)
+}
+
+func (r *csvRecord) field(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = r.ends[i-1]
+	}
+	return r.buf[start:r.ends[i]]
+}
+
+// split разбивает строку на поля. Поля в кавычках могут содержать разделитель, кавычка
+// внутри них удваивается.
+func (r *csvRecord) split(line []byte, comma, quote byte) error {
+	r.buf = r.buf[:0]
+	r.ends = r.ends[:0]
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+
+	for {
+		if quote != 0 && len(line) > 0 && line[0] == quote {
+			line = line[1:]
+			for {
+				i := bytes.IndexByte(line, quote)
+				if i < 0 {
+					return ErrUnterminatedQuote
+				}
+				r.buf = append(r.buf, line[:i]...)
+				line = line[i+1:]
+				if len(line) > 0 && line[0] == quote {
+					r.buf = append(r.buf, quote)
+					line = line[1:]
+					continue
+				}
+				break
+			}
+			if len(line) > 0 && line[0] != comma {
+				return ErrBareQuote
+			}
+		} else {
+			i := bytes.IndexByte(line, comma)
+			if i < 0 {
+				i = len(line)
+			}
+			r.buf = append(r.buf, line[:i]...)
+			line = line[i:]
+		}
+
+		r.ends = append(r.ends, len(r.buf))
+		if len(line) == 0 {
+			return nil
+		}
+		line = line[1:] // разделитель
+	}
+}
+
+var _ scanner.Parser = &CSVParser{}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"pg-bulk-flow/internal/model"
+	"pg-bulk-flow/internal/scanner"
+)
+
+func TestCSVParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		comma   byte
+		quote   byte
+		columns string
+		header  string
+		input   string
+		want    model.Name
+		stage   scanner.Stage
+	}{
+		{"Plain", ',', '"', "text=1,count=2,gender=3", "", `Иванов,10,m`,
+			model.Name{Text: "Иванов", Count: 10, Gender: model.GenderMale}, ""},
+		{"Reordered", ',', '"', "text=1,count=3,gender=2", "", `Иванова,f,7`,
+			model.Name{Text: "Иванова", Count: 7, Gender: model.GenderFemale}, ""},
+		{"Quoted", ',', '"', "text=1,count=2", "", `"Смит, ""Мл.""",3`,
+			model.Name{Text: `Смит, "Мл."`, Count: 3}, ""},
+		{"TSV", '\t', 0, "text=2,count=1", "", "5\t\"Петров\"\r",
+			model.Name{Text: `"Петров"`, Count: 5}, ""},
+		{"Custom quote", ';', '\'', "text=1,gender=2", "", `'O''Brien';m`,
+			model.Name{Text: "O'Brien", Count: 1, Gender: model.GenderMale}, ""},
+		{"Header", ',', '"', "text=surname,count=Freq", "freq,surname", `42,Сидоров`,
+			model.Name{Text: "Сидоров", Count: 42}, ""},
+		{"Unterminated quote", ',', '"', "text=1", "", `"Иванов`, model.Name{}, scanner.StageCSV},
+		{"Bare quote", ',', '"', "text=1", "", `"Ива"нов,1`, model.Name{}, scanner.StageCSV},
+		{"Missing field", ',', '"', "text=1,count=3", "", `Иванов,1`, model.Name{}, scanner.StageCSV},
+		{"Empty text", ',', '"', "text=1,count=2", "", ` ,1`, model.Name{}, scanner.StageEmptyFields},
+		{"Bad gender", ',', '"', "text=1,gender=2", "", `Иванов,x`, model.Name{}, scanner.StageGender},
+		{"Bad count", ',', '"', "text=1,count=2", "", `Иванов,abc`, model.Name{}, scanner.StageCount},
+		{"Zero count", ',', '"', "text=1,count=2", "", `Иванов,0`, model.Name{}, scanner.StageCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := NewCSVFormat(tt.comma, tt.quote, tt.columns)
+			if err != nil {
+				t.Fatalf("NewCSVFormat: %v", err)
+			}
+			if format.NeedsHeader() != (tt.header != "") {
+				t.Fatalf("NeedsHeader() = %v", format.NeedsHeader())
+			}
+			if tt.header != "" {
+				if err := format.ParseHeader([]byte(tt.header)); err != nil {
+					t.Fatalf("ParseHeader: %v", err)
+				}
+			}
+
+			p := NewCSVParser(format)
+			got, err := p.Parse(context.Background(), []byte(tt.input))
+
+			var se *scanner.StageError
+			switch {
+			case tt.stage == "" && err != nil:
+				t.Fatalf("Parse error = %v", err)
+			case tt.stage != "" && !errors.As(err, &se):
+				t.Fatalf("Parse error = %v, want stage %s", err, tt.stage)
+			case tt.stage != "" && se.Stage != tt.stage:
+				t.Fatalf("stage = %s, want %s", se.Stage, tt.stage)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+			if st := p.Stats(); st.InvalidJSON != 0 || (st.InvalidCSV == 1) != (tt.stage == scanner.StageCSV) {
+				t.Errorf("Stats = %+v", st)
+			}
+		})
+	}
+}
+
+func TestCSVFormatErrors(t *testing.T) {
+	for _, columns := range []string{"", "count=1", "text=0", "text", "name=1"} {
+		if _, err := NewCSVFormat(',', '"', columns); err == nil {
+			t.Errorf("NewCSVFormat(%q) error = nil", columns)
+		}
+	}
+
+	format, err := NewCSVFormat(',', '"', "text=surname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := format.ParseHeader([]byte("name,count")); err == nil {
+		t.Error("ParseHeader error = nil for missing column")
+	}
+}
+
+func TestCSVFormatAppend(t *testing.T) {
+	const text = `Смит, "Мл."`
+	tests := []struct {
+		name    string
+		comma   byte
+		quote   byte
+		columns string
+		header  string
+		record  string
+		want    model.Name
+	}{
+		{"Numbers", ',', '"', "text=1,count=2,gender=3", "", `"Смит, ""Мл.""",5,female` + "\n",
+			model.Name{Text: text, Count: 5, Gender: model.GenderFemale}},
+		{"Gaps", '\t', 0, "text=3,gender=1", "", "female\t\t" + text + "\n",
+			model.Name{Text: text, Count: 1, Gender: model.GenderFemale}},
+		{"Names", ',', '"', "text=surname,count=freq", "surname,freq\n", `"Смит, ""Мл.""",5` + "\n",
+			model.Name{Text: text, Count: 5}},
+		{"Mixed", ';', '"', "text=surname,count=1,gender=sex", ";surname;sex\n", `5;"Смит, ""Мл.""";female` + "\n",
+			model.Name{Text: text, Count: 5, Gender: model.GenderFemale}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := NewCSVFormat(tt.comma, tt.quote, tt.columns)
+			if err != nil {
+				t.Fatalf("NewCSVFormat: %v", err)
+			}
+			if format.NeedsHeader() {
+				header := format.AppendHeader(nil)
+				if string(header) != tt.header {
+					t.Fatalf("AppendHeader = %q, want %q", header, tt.header)
+				}
+				if err := format.ParseHeader(bytes.TrimSuffix(header, []byte{'\n'})); err != nil {
+					t.Fatalf("ParseHeader: %v", err)
+				}
+			}
+
+			record := format.AppendRecord(nil, text, "5", "female")
+			if string(record) != tt.record {
+				t.Fatalf("AppendRecord = %q, want %q", record, tt.record)
+			}
+
+			got, err := NewCSVParser(format).Parse(context.Background(), bytes.TrimSuffix(record, []byte{'\n'}))
+			if err != nil {
+				t.Fatalf("Parse error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
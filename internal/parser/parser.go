@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 
 	"pg-bulk-flow/internal/model"
 	"pg-bulk-flow/internal/scanner"
@@ -16,10 +17,9 @@ import (
 // с тегом nocopy. Использование nocopy здесь безопасно поскольку:
 //   - Count в конечном счете копируется в int32 (numberLong — кастомный тип для парсинга
 //     сложных объектов в int64, реализует json.Unmarshaler);
-//   - Text неявно клонируется функцией model.NormalizeName;
+//   - Text, FName, FForm и MForm неявно клонируются функцией model.NormalizeName;
 //   - Gender парсится в model.Gender (byte);
-//
-// Остальные поля только проверяются на zero-value.
+//   - элементы Ethnic клонируются (интернируются) в Parser.ethnic.
 //
 //easyjson:json
 type inputRecord struct {
@@ -34,16 +34,55 @@ type inputRecord struct {
 
 type Stats struct {
 	InvalidJSON   int `json:"invalid_json,omitempty"`
+	InvalidCSV    int `json:"invalid_csv,omitempty"` // синтаксис CSV/TSV или не хватает колонок
 	EmptyFields   int `json:"empty_fields,omitempty"`
 	InvalidName   int `json:"invalid_name,omitempty"`
 	InvalidGender int `json:"invalid_gender,omitempty"`
 	InvalidCount  int `json:"invalid_count,omitempty"`
 }
 
+// Add суммирует статистику, например, нескольких параллельно работавших парсеров.
+func (s *Stats) Add(o Stats) {
+	s.InvalidJSON += o.InvalidJSON
+	s.InvalidCSV += o.InvalidCSV
+	s.EmptyFields += o.EmptyFields
+	s.InvalidName += o.InvalidName
+	s.InvalidGender += o.InvalidGender
+	s.InvalidCount += o.InvalidCount
+}
+
 // Parse парсит входные данные в model.Name.
 // Парсер НЕ потокобезопасен. Создавайте новый для каждой горутины.
 type Parser struct {
-	stats Stats
+	stats  Stats
+	ethnic map[string]string
+}
+
+// maxEthnic ограничение на количество интернированных значений ethnic. Различных
+// значений в реальных данных единицы, ограничение защищает от мусора во входе.
+const maxEthnic = 1024
+
+// internEthnic копирует массив ethnic, не удерживающий ссылок на входной буфер.
+// Строки интернируются: повторяющиеся значения не аллоцируются заново.
+func (p *Parser) internEthnic(src []string) []string {
+	if src == nil {
+		return nil
+	}
+	dst := make([]string, len(src))
+	for i, s := range src {
+		v, ok := p.ethnic[s]
+		if !ok {
+			v = strings.Clone(s)
+			if len(p.ethnic) < maxEthnic {
+				if p.ethnic == nil {
+					p.ethnic = make(map[string]string)
+				}
+				p.ethnic[v] = v
+			}
+		}
+		dst[i] = v
+	}
+	return dst
 }
 
 func (p *Parser) Stats() Stats {
@@ -54,38 +93,50 @@ func (p *Parser) Parse(ctx context.Context, data []byte) (model.Name, error) {
 	var rec inputRecord
 	if err := rec.UnmarshalJSON(data); err != nil {
 		p.stats.InvalidJSON++
-		return model.Name{}, err
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageJSON, Err: err}
 	}
 
 	if rec.Count == 1 && rec.Gender == "" && rec.FName == "" && rec.FForm == "" && rec.MForm == "" && rec.Ethnic == nil {
 		// Запись игнорируется: все поля пусты или nil (скорее всего мусор).
 		// Полезные данные обычно содержат хотя бы одно дополнительное поле.
 		p.stats.EmptyFields++
-		return model.Name{}, errors.New("too little data")
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageEmptyFields, Err: errors.New("too little data")}
 	}
 
 	// Остальное — ответственность валидатора
 	name, err := model.NormalizeName(rec.Text)
 	if err != nil {
 		p.stats.InvalidName++
-		return model.Name{}, fmt.Errorf("invalid text: %w", err)
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageName, Err: fmt.Errorf("invalid text: %w", err)}
+	}
+
+	var forms [3]string
+	for i, s := range [...]string{rec.FName, rec.FForm, rec.MForm} {
+		if forms[i], err = model.NormalizeName(s); err != nil {
+			p.stats.InvalidName++
+			return model.Name{}, &scanner.StageError{Stage: scanner.StageName, Err: fmt.Errorf("invalid form: %w", err)}
+		}
 	}
 
 	gender, err := model.ParseGender(rec.Gender)
 	if err != nil {
 		p.stats.InvalidGender++
-		return model.Name{}, fmt.Errorf("invalid gender: %w", err)
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageGender, Err: fmt.Errorf("invalid gender: %w", err)}
 	}
 
 	if !(0 < rec.Count && rec.Count <= math.MaxInt32) {
 		p.stats.InvalidCount++
-		return model.Name{}, fmt.Errorf("count must be [1..%d]", math.MaxInt32)
+		return model.Name{}, &scanner.StageError{Stage: scanner.StageCount, Err: fmt.Errorf("count must be [1..%d]", math.MaxInt32)}
 	}
 
 	return model.Name{
 		Text:   name,
 		Gender: gender,
 		Count:  int32(rec.Count),
+		FName:  forms[0],
+		FForm:  forms[1],
+		MForm:  forms[2],
+		Ethnic: p.internEthnic(rec.Ethnic),
 	}, nil
 }
 
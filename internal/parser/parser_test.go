@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"pg-bulk-flow/internal/model"
+)
+
+func TestParserForms(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  model.Name
+	}{
+		{
+			name:  "Forms",
+			input: `{"text":" Иванов","count":3,"f_form":"Иванова ","m_form":"Иванов","ethnic":["slav","tat"]}`,
+			want: model.Name{Text: "Иванов", Count: 3, FForm: "Иванова", MForm: "Иванов",
+				Ethnic: []string{"slav", "tat"}},
+		},
+		{
+			name:  "FName",
+			input: `{"text":"Петрович","count":2,"fname":" Петровна"}`,
+			want:  model.Name{Text: "Петрович", Count: 2, FName: "Петровна"},
+		},
+		{
+			name:  "EmptyEthnic",
+			input: `{"text":"Ли","count":2,"ethnic":[]}`,
+			want:  model.Name{Text: "Ли", Count: 2, Ethnic: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			data := []byte(tt.input)
+			got, err := p.Parse(context.Background(), data)
+			if err != nil {
+				t.Fatalf("Parse error = %v", err)
+			}
+			// строки не должны ссылаться на входной буфер
+			clear(data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
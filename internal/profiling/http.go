@@ -0,0 +1,43 @@
+package profiling
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	httppprof "net/http/pprof"
+	"time"
+)
+
+// Serve запускает HTTP-сервер net/http/pprof на addr и возвращает управление сразу
+// после открытия порта, поэтому ошибка адреса видна вызывающему. Сервер работает до
+// завершения процесса.
+//
+// Профили mutex и block наполняются, только если включен их сбор: на время работы
+// их включают флаги -mutexprofile и -blockprofile.
+func Serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	slog.Info("pprof server started", "addr", ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("pprof server failed", "error", err)
+		}
+	}()
+	return nil
+}
@@ -7,15 +7,19 @@ import (
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
 )
 
 var (
 	cpuprofile   = flag.String("cpuprofile", "", "write CPU profile to `file`")
 	memprofile   = flag.String("memprofile", "", "write memory profile to `file`")
 	blockprofile = flag.String("blockprofile", "", "write block profile to `file`")
+	mutexprofile = flag.String("mutexprofile", "", "write mutex contention profile to `file`")
+	traceout     = flag.String("trace", "", "write execution trace to `file`")
 )
 
-// Start starts profiling if the -cpuprofile, -memprofile or -blockprofile flags are set
+// Start starts profiling if the -cpuprofile, -memprofile, -blockprofile, -mutexprofile
+// or -trace flags are set
 // To use it, add the following code to your main function:
 //
 //	flags.Parse()
@@ -25,7 +29,15 @@ var (
 func Start() func() {
 	stopCPUProfile := startCPUProfile()
 	stopBlockProfile := startBlockProfile()
+	stopMutexProfile := startMutexProfile()
+	stopTrace := startTrace()
 	return func() {
+		if stopTrace != nil {
+			stopTrace()
+		}
+		if stopMutexProfile != nil {
+			stopMutexProfile()
+		}
 		if stopBlockProfile != nil {
 			stopBlockProfile()
 		}
@@ -129,3 +141,61 @@ func saveBlockProfile() {
 		return
 	}
 }
+
+func startMutexProfile() func() {
+	if *mutexprofile == "" {
+		return nil
+	}
+	runtime.SetMutexProfileFraction(1)
+	return func() {
+		runtime.SetMutexProfileFraction(0)
+		saveMutexProfile()
+	}
+}
+
+func saveMutexProfile() {
+	f, err := os.Create(*mutexprofile)
+	if err != nil {
+		slog.Error("could not create mutex profile", "error", err)
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			slog.Warn("could not close mutex profile", "error", err)
+		}
+	}()
+
+	if err := pprof.Lookup("mutex").WriteTo(f, 0); err != nil {
+		slog.Error("could not write mutex profile", "error", err)
+		return
+	}
+}
+
+func startTrace() func() {
+	if *traceout == "" {
+		return nil
+	}
+
+	f, err := os.Create(*traceout)
+	if err != nil {
+		slog.Error("could not create trace", "error", err)
+		return nil
+	}
+	w := bufio.NewWriter(f)
+
+	if err := trace.Start(w); err != nil {
+		slog.Error("could not start trace", "error", err)
+		f.Close()
+		return nil
+	}
+
+	return func() {
+		trace.Stop()
+		if err := w.Flush(); err != nil {
+			slog.Error("could not flush trace", "error", err)
+		}
+		if err := f.Close(); err != nil {
+			slog.Warn("could not close trace", "error", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package progress
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Counters значения счетчиков загрузки в момент опроса.
+type Counters struct {
+	Lines int64 // прочитано строк входа
+	Rows  int64 // записей передано в базу
+	Bytes int64 // прочитано байт из источника (до распаковки)
+}
+
+// Sample оценка прогресса между двумя опросами.
+type Sample struct {
+	Counters
+	Elapsed time.Duration
+	Rate    float64       // записей в секунду за последний интервал
+	AvgRate float64       // записей в секунду с начала загрузки
+	Percent float64       // доля прочитанного входа, 0 если размер неизвестен
+	ETA     time.Duration // оценка оставшегося времени, 0 если размер неизвестен
+}
+
+// Estimate считает Sample по предыдущему и текущему опросу. total — размер входа в
+// байтах (0, если неизвестен, например для stdin). ETA экстраполирует среднюю скорость
+// чтения входа: при сжатом входе сравниваются байты файла, а не распакованные.
+func Estimate(prev, cur Counters, dt, elapsed time.Duration, total int64) Sample {
+	s := Sample{Counters: cur, Elapsed: elapsed}
+	if dt > 0 {
+		s.Rate = float64(cur.Rows-prev.Rows) / dt.Seconds()
+	}
+	if elapsed > 0 {
+		s.AvgRate = float64(cur.Rows) / elapsed.Seconds()
+	}
+	if total > 0 && cur.Bytes > 0 {
+		done := min(float64(cur.Bytes)/float64(total), 1)
+		s.Percent = done * 100
+		s.ETA = time.Duration(float64(elapsed) * (1 - done) / done).Round(time.Second)
+	}
+	return s
+}
+
+// attrs поля записи лога.
+func (s Sample) attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int64("lines", s.Lines),
+		slog.Int64("rows", s.Rows),
+		slog.Int64("bytes", s.Bytes),
+		slog.Int64("rps", int64(s.Rate)),
+		slog.Int64("avg_rps", int64(s.AvgRate)),
+		slog.Duration("elapsed", s.Elapsed.Round(time.Second)),
+	}
+	if s.Percent > 0 {
+		attrs = append(attrs,
+			slog.Float64("percent", float64(int(s.Percent*10))/10),
+			slog.Duration("eta", s.ETA),
+		)
+	}
+	return attrs
+}
+
+// Run каждые interval пишет в лог прогресс по счетчикам из read, пока не отменен ctx.
+func Run(ctx context.Context, interval time.Duration, total int64, read func() Counters) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last, prev := start, Counters{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cur := read()
+			s := Estimate(prev, cur, now.Sub(last), now.Sub(start), total)
+			slog.LogAttrs(ctx, slog.LevelInfo, "progress", s.attrs()...)
+			last, prev = now, cur
+		}
+	}
+}
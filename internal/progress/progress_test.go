@@ -0,0 +1,59 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur Counters
+		dt        time.Duration
+		elapsed   time.Duration
+		total     int64
+		want      Sample
+	}{
+		{
+			name:    "UnknownSize",
+			prev:    Counters{Rows: 1000},
+			cur:     Counters{Lines: 4000, Rows: 3000, Bytes: 500},
+			dt:      2 * time.Second,
+			elapsed: 10 * time.Second,
+			want: Sample{Counters: Counters{Lines: 4000, Rows: 3000, Bytes: 500},
+				Elapsed: 10 * time.Second, Rate: 1000, AvgRate: 300},
+		},
+		{
+			name:    "Quarter",
+			cur:     Counters{Rows: 100, Bytes: 250},
+			dt:      time.Second,
+			elapsed: 10 * time.Second,
+			total:   1000,
+			want: Sample{Counters: Counters{Rows: 100, Bytes: 250},
+				Elapsed: 10 * time.Second, Rate: 100, AvgRate: 10, Percent: 25, ETA: 30 * time.Second},
+		},
+		{
+			// файл мог вырасти после открытия
+			name:    "BeyondSize",
+			cur:     Counters{Bytes: 2000},
+			elapsed: time.Second,
+			total:   1000,
+			want: Sample{Counters: Counters{Bytes: 2000},
+				Elapsed: time.Second, Percent: 100},
+		},
+		{
+			name:  "Start",
+			total: 1000,
+			want:  Sample{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Estimate(tt.prev, tt.cur, tt.dt, tt.elapsed, tt.total)
+			if got != tt.want {
+				t.Errorf("Estimate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
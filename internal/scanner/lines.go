@@ -0,0 +1,55 @@
+package scanner
+
+import "bytes"
+
+// lineSplitter аналог bufio.ScanLines, который пропускает строки длиннее max вместо
+// ошибки bufio.ErrTooLong. На месте пропущенной строки возвращается пустой токен,
+// а флаг oversize выставляется до следующего вызова.
+type lineSplitter struct {
+	max      int  // должен совпадать с максимальным размером буфера bufio.Scanner
+	skipping bool // пропускаем хвост слишком длинной строки
+	oversize bool // последний токен — пропущенная строка
+}
+
+var emptyToken = []byte{}
+
+func (ls *lineSplitter) split(data []byte, atEOF bool) (int, []byte, error) {
+	if ls.skipping {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			ls.skipping = false
+			return i + 1, emptyToken, nil
+		}
+		if atEOF {
+			ls.skipping = false
+			return len(data), emptyToken, nil
+		}
+		return len(data), nil, nil
+	}
+
+	ls.oversize = false
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, dropCR(data[:i]), nil
+	}
+	if len(data) >= ls.max {
+		ls.oversize = true
+		if atEOF {
+			return len(data), emptyToken, nil
+		}
+		ls.skipping = true
+		return len(data), nil, nil
+	}
+	if atEOF {
+		return len(data), dropCR(data), nil
+	}
+	return 0, nil, nil
+}
+
+func dropCR(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == '\r' {
+		return data[:len(data)-1]
+	}
+	return data
+}
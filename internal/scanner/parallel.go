@@ -0,0 +1,233 @@
+package scanner
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"pg-bulk-flow/internal/logger"
+	"pg-bulk-flow/internal/model"
+)
+
+// parseChunkLines количество строк в порции, передаваемой воркеру.
+const parseChunkLines = 1024
+
+type lineRef struct {
+	end      int // конец строки в lineChunk.data
+	oversize bool
+}
+
+// lineChunk порция строк. Строки хранятся подряд в data, чтобы не аллоцировать каждую.
+type lineChunk struct {
+	seq     int
+	first   int // номер первой строки
+	data    []byte
+	lines   []lineRef
+	results []parseResult
+}
+
+func (c *lineChunk) reset() {
+	c.data = c.data[:0]
+	c.lines = c.lines[:0]
+	clear(c.results)
+	c.results = c.results[:0]
+}
+
+func (c *lineChunk) line(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = c.lines[i-1].end
+	}
+	return c.data[start:c.lines[i].end]
+}
+
+// scanParallel читает строки в отдельной горутине, разбирает их len(s.parsers) воркерами
+// и собирает результаты в горутине потребителя. Статистика и rejecter обновляются только
+// потребителем, поэтому синхронизация им не нужна.
+func (s *Scanner) scanParallel(ctx context.Context) iter.Seq[model.Name] {
+	log := logger.FromContext(ctx).With("op", "Scan")
+	sc, ls := s.newLineScanner()
+	workers := len(s.parsers)
+
+	return func(yield func(model.Name) bool) {
+		done := make(chan struct{})
+		exited := make(chan struct{})
+
+		// Число порций ограничено, это же ограничивает память при упорядоченной сборке
+		free := make(chan *lineChunk, 4*workers)
+		for range cap(free) {
+			free <- &lineChunk{}
+		}
+		work := make(chan *lineChunk, workers)
+		results := make(chan *lineChunk, workers)
+
+		var readErr error
+		go func() {
+			defer close(exited)
+			defer close(work)
+
+			var (
+				lineNum int
+				seq     int
+				c       *lineChunk
+			)
+			for sc.Scan() {
+				if stopped(ctx) {
+					return
+				}
+				select {
+				case <-done:
+					return
+				default:
+				}
+				lineNum++
+				s.lines.Store(int64(lineNum))
+
+				// Заголовок разбирается до запуска первой порции, поэтому воркеры
+				// видят его результат
+				if lineNum == 1 && s.header != nil {
+					if err := s.header(sc.Bytes()); err != nil {
+						log.Error("invalid header", "error", err)
+						readErr = ErrScanFailed
+						return
+					}
+					continue
+				}
+
+				if lineNum <= s.skipLines {
+					s.stats.Skipped++
+					continue
+				}
+
+				if c == nil {
+					select {
+					case c = <-free:
+					case <-done:
+						return
+					}
+					c.reset()
+					c.seq, c.first = seq, lineNum
+					seq++
+				}
+
+				if ls != nil && ls.oversize {
+					c.lines = append(c.lines, lineRef{end: len(c.data), oversize: true})
+				} else {
+					c.data = append(c.data, sc.Bytes()...)
+					c.lines = append(c.lines, lineRef{end: len(c.data)})
+				}
+
+				if len(c.lines) >= parseChunkLines {
+					select {
+					case work <- c:
+						c = nil
+					case <-done:
+						return
+					}
+				}
+			}
+
+			if c != nil {
+				select {
+				case work <- c:
+				case <-done:
+					return
+				}
+			}
+
+			if err := sc.Err(); err != nil {
+				log.Error("scan failed", "error", err, "lineNum", lineNum+1)
+				readErr = ErrScanFailed
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for _, p := range s.parsers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for c := range work {
+					for i, l := range c.lines {
+						r := parseResult{stage: StageOversize, err: s.oversizeErr()}
+						if !l.oversize {
+							r = s.parseLine(ctx, p, c.line(i))
+						}
+						c.results = append(c.results, r)
+					}
+					select {
+					case results <- c:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		// При раннем выходе читатель и воркеры должны завершиться до возврата: они
+		// пишут статистику сканера и парсеров, которую вызывающий читает сразу после
+		defer func() {
+			close(done)
+			for range results {
+			}
+			<-exited
+		}()
+
+		emit := func(c *lineChunk) bool {
+			for i, r := range c.results {
+				lineNum := c.first + i
+				// разобранные, но еще не отданные записи после отмены отбрасываются,
+				// как и непрочитанные строки в последовательном режиме
+				if stopped(ctx) {
+					log.Info("scan interrupted", "lineNum", lineNum)
+					return false
+				}
+				var raw []byte
+				if !c.lines[i].oversize {
+					raw = c.line(i)
+				}
+				if !s.account(log, lineNum, raw, r) {
+					continue
+				}
+				r.name.Line = lineNum
+				if !yield(r.name) {
+					log.Warn("scan break", "lineNum", lineNum)
+					return false
+				}
+			}
+			free <- c // не блокируется: емкость free равна числу порций
+			return true
+		}
+
+		pending := make(map[int]*lineChunk)
+		next := 0
+		for c := range results {
+			if !s.ordered {
+				if !emit(c) {
+					return
+				}
+				continue
+			}
+
+			pending[c.seq] = c
+			for {
+				c, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !emit(c) {
+					return
+				}
+			}
+		}
+
+		// results закрыт только после close(work), поэтому readErr уже записан
+		s.err = readErr
+	}
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"pg-bulk-flow/internal/model"
+)
+
+// Stage этап обработки, на котором строка была отброшена.
+type Stage string
+
+const (
+	StageOversize    Stage = "oversize"
+	StageJSON        Stage = "json"
+	StageCSV         Stage = "csv"
+	StageEmptyFields Stage = "empty_fields"
+	StageName        Stage = "name"
+	StageGender      Stage = "gender"
+	StageCount       Stage = "count"
+	StageValidate    Stage = "validate"
+)
+
+// StageError ошибка парсера с указанием этапа, на котором она возникла.
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Reject описывает отброшенную строку входных данных.
+type Reject struct {
+	Line   int          `json:"line"`
+	Raw    string       `json:"raw"`
+	Stage  Stage        `json:"stage"`
+	Reason model.Reason `json:"reason,omitempty"` // только для StageValidate
+	Error  string       `json:"error"`
+}
+
+// Rejecter получает строки, отброшенные сканером.
+type Rejecter interface {
+	Reject(r Reject) error
+}
+
+// RejectWriter пишет отброшенные строки в формате JSONL.
+type RejectWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewRejectWriter(w io.Writer) *RejectWriter {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &RejectWriter{
+		w:   bw,
+		enc: enc,
+	}
+}
+
+// Reject implements Rejecter.
+func (rw *RejectWriter) Reject(r Reject) error {
+	return rw.enc.Encode(r)
+}
+
+func (rw *RejectWriter) Flush() error {
+	return rw.w.Flush()
+}
+
+var _ Rejecter = &RejectWriter{}
@@ -2,10 +2,15 @@ package scanner
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"iter"
+	"log/slog"
+	"maps"
+	"sync/atomic"
 
 	"pg-bulk-flow/internal/logger"
 	"pg-bulk-flow/internal/model"
@@ -19,14 +24,28 @@ type Stats struct {
 	Total    int `json:"total,omitempty"`    // общее количество прочитанных записей
 	Unparsed int `json:"unparsed,omitempty"` // записи забракованные парсером
 	Invalid  int `json:"invalid,omitempty"`  // записи не прошедшие валидацию
+	Oversize int `json:"oversize,omitempty"` // пропущенные строки длиннее максимального размера
+	Inferred int `json:"inferred,omitempty"` // записи, пол которых определен по имени
+	Skipped  int `json:"skipped,omitempty"`  // строки, пропущенные при продолжении загрузки
+
+	InvalidReasons map[model.Reason]int `json:"invalid_reasons,omitempty"` // Invalid в разбивке по причинам
 }
 
 type Scanner struct {
-	reader   io.Reader
-	nameType model.NameType
-	parser   Parser
-	stats    Stats
-	err      error
+	reader       io.Reader
+	nameType     model.NameType
+	parser       Parser
+	parsers      []Parser
+	ordered      bool
+	rejecter     Rejecter
+	inferGender  bool
+	header       func(line []byte) error
+	maxLine      int
+	skipOversize bool
+	skipLines    int
+	lines        atomic.Int64 // прочитано строк, обновляется во время Scan
+	stats        Stats
+	err          error
 }
 
 func New(r io.Reader, nameType model.NameType, parser Parser) *Scanner {
@@ -37,8 +56,57 @@ func New(r io.Reader, nameType model.NameType, parser Parser) *Scanner {
 	}
 }
 
+// SetRejecter задает получателя отброшенных строк. По умолчанию они только подсчитываются.
+func (s *Scanner) SetRejecter(r Rejecter) {
+	s.rejecter = r
+}
+
+// SetMaxLine задает максимальный размер строки в байтах (по умолчанию
+// bufio.MaxScanTokenSize). Если skip, то более длинные строки пропускаются и учитываются
+// в Stats.Oversize, иначе сканирование завершается с ErrScanFailed.
+func (s *Scanner) SetMaxLine(n int, skip bool) {
+	s.maxLine = n
+	s.skipOversize = skip
+}
+
+// SetParseWorkers включает параллельный разбор: каждый парсер из parsers работает в своей
+// горутине над порциями строк. Если ordered, то записи отдаются в порядке входных данных.
+// Статистику парсеров вызывающий код сводит сам.
+func (s *Scanner) SetParseWorkers(parsers []Parser, ordered bool) {
+	if len(parsers) == 1 {
+		s.parser = parsers[0]
+	}
+	s.parsers = parsers
+	s.ordered = ordered
+}
+
+// SetInferGender включает определение неизвестного пола по имени (см. model.DetectGender).
+func (s *Scanner) SetInferGender(v bool) {
+	s.inferGender = v
+}
+
+// SetHeader указывает, что первая строка — заголовок. Она передается в fn и не считается
+// записью. Ошибка fn прерывает сканирование с ErrScanFailed.
+func (s *Scanner) SetHeader(fn func(line []byte) error) {
+	s.header = fn
+}
+
+// SetSkipLines пропускает строки входа с номерами до n включительно, не разбирая их
+// (например, уже загруженные в прошлый раз). Заголовок по-прежнему разбирается.
+func (s *Scanner) SetSkipLines(n int) {
+	s.skipLines = n
+}
+
+// Lines возвращает количество прочитанных строк входа, включая заголовок и пропущенные.
+// В отличие от Stats, его можно вызывать из другой горутины во время Scan.
+func (s *Scanner) Lines() int64 {
+	return s.lines.Load()
+}
+
 func (p *Scanner) Stats() Stats {
-	return p.stats
+	stats := p.stats
+	stats.InvalidReasons = maps.Clone(p.stats.InvalidReasons)
+	return stats
 }
 
 func (p *Scanner) Err() error {
@@ -48,30 +116,49 @@ func (p *Scanner) Err() error {
 var ErrScanFailed = errors.New("scan failed")
 
 func (s *Scanner) Scan(ctx context.Context) iter.Seq[model.Name] {
+	if len(s.parsers) > 1 {
+		return s.scanParallel(ctx)
+	}
+
 	log := logger.FromContext(ctx).With("op", "Scan")
-	sc := bufio.NewScanner(s.reader)
+	sc, ls := s.newLineScanner()
 
 	return func(yield func(model.Name) bool) {
 		var lineNum = 0
 		for sc.Scan() {
+			if stopped(ctx) {
+				log.Info("scan interrupted", "lineNum", lineNum)
+				return
+			}
 			lineNum++
-			s.stats.Total++
+			s.lines.Store(int64(lineNum))
+
+			if lineNum == 1 && s.header != nil {
+				if err := s.header(sc.Bytes()); err != nil {
+					log.Error("invalid header", "error", err)
+					s.err = ErrScanFailed
+					return
+				}
+				continue
+			}
+
+			if lineNum <= s.skipLines {
+				s.stats.Skipped++
+				continue
+			}
 
-			name, err := s.parser.Parse(ctx, sc.Bytes())
-			if err != nil {
-				s.stats.Unparsed++
-				log.Debug("skip bad line", "error", err, "lineNum", lineNum)
+			if ls != nil && ls.oversize {
+				s.account(log, lineNum, nil, parseResult{stage: StageOversize, err: s.oversizeErr()})
 				continue
 			}
 
-			name.Type = s.nameType
-			if err := name.Validate(); err != nil {
-				s.stats.Invalid++
-				log.Debug("invalid record", "error", err, "lineNum", lineNum)
+			r := s.parseLine(ctx, s.parser, sc.Bytes())
+			if !s.account(log, lineNum, sc.Bytes(), r) {
 				continue
 			}
 
-			if !yield(name) {
+			r.name.Line = lineNum
+			if !yield(r.name) {
 				log.Warn("scan break", "lineNum", lineNum)
 				break
 			}
@@ -83,3 +170,131 @@ func (s *Scanner) Scan(ctx context.Context) iter.Seq[model.Name] {
 		}
 	}
 }
+
+// stopped сообщает без блокировки, отменен ли ctx. Отмена прекращает чтение, но не
+// считается ошибкой сканирования: уже прочитанные записи остаются валидными.
+func stopped(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Scanner) lineLimit() int {
+	return cmp.Or(s.maxLine, bufio.MaxScanTokenSize)
+}
+
+func (s *Scanner) oversizeErr() error {
+	return fmt.Errorf("line exceeds %d bytes", s.lineLimit())
+}
+
+// newLineScanner возвращает построчный сканер входных данных. Если включен пропуск
+// длинных строк, то возвращается и lineSplitter, отмечающий такие строки.
+func (s *Scanner) newLineScanner() (*bufio.Scanner, *lineSplitter) {
+	sc := bufio.NewScanner(s.reader)
+
+	// +1 на перевод строки
+	maxLine := s.lineLimit()
+	sc.Buffer(nil, maxLine+1)
+
+	var ls *lineSplitter
+	if s.skipOversize {
+		ls = &lineSplitter{max: maxLine + 1}
+		sc.Split(ls.split)
+	}
+
+	return sc, ls
+}
+
+// parseResult результат обработки одной строки.
+type parseResult struct {
+	name     model.Name
+	stage    Stage // этап, на котором строка отброшена (пусто, если запись валидна)
+	err      error
+	inferred bool // пол определен по имени
+}
+
+// parseLine парсит и валидирует строку. При ошибке возвращает этап, на котором она возникла.
+func (s *Scanner) parseLine(ctx context.Context, p Parser, line []byte) parseResult {
+	name, err := p.Parse(ctx, line)
+	if err != nil {
+		stage := StageJSON
+		var se *StageError
+		if errors.As(err, &se) {
+			stage = se.Stage
+		}
+		return parseResult{stage: stage, err: err}
+	}
+
+	name.Type = s.nameType
+
+	var inferred bool
+	if s.inferGender && name.Gender == model.GenderUnknown {
+		name.Gender = model.DetectGender(name.Text, name.Type)
+		inferred = name.Gender != model.GenderUnknown
+	}
+
+	if err := name.Validate(); err != nil {
+		return parseResult{stage: StageValidate, err: err}
+	}
+
+	return parseResult{name: name, inferred: inferred}
+}
+
+// account учитывает результат обработки строки в статистике и, при ошибке, отправляет
+// строку в rejecter. Возвращает true, если запись нужно отдать потребителю.
+func (s *Scanner) account(log *slog.Logger, lineNum int, raw []byte, r parseResult) bool {
+	s.stats.Total++
+
+	switch r.stage {
+	case "":
+		if r.inferred {
+			s.stats.Inferred++
+		}
+		return true
+	case StageOversize:
+		s.stats.Oversize++
+		log.Debug("skip oversize line", "lineNum", lineNum)
+	case StageValidate:
+		s.stats.Invalid++
+		if s.stats.InvalidReasons == nil {
+			s.stats.InvalidReasons = make(map[model.Reason]int)
+		}
+		s.stats.InvalidReasons[validationReason(r.err)]++
+		log.Debug("invalid record", "error", r.err, "lineNum", lineNum)
+	default:
+		s.stats.Unparsed++
+		log.Debug("skip bad line", "error", r.err, "lineNum", lineNum)
+	}
+
+	s.reject(log, lineNum, raw, r.stage, r.err)
+	return false
+}
+
+func validationReason(err error) model.Reason {
+	var ve *model.ValidationError
+	if errors.As(err, &ve) {
+		return ve.Reason
+	}
+	return "unknown"
+}
+
+func (s *Scanner) reject(log *slog.Logger, lineNum int, raw []byte, stage Stage, err error) {
+	if s.rejecter == nil {
+		return
+	}
+	r := Reject{
+		Line:  lineNum,
+		Raw:   string(raw),
+		Stage: stage,
+		Error: err.Error(),
+	}
+	if stage == StageValidate {
+		r.Reason = validationReason(err)
+	}
+	if err := s.rejecter.Reject(r); err != nil {
+		log.Warn("write reject failed", "error", err, "lineNum", lineNum)
+	}
+}
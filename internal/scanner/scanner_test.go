@@ -0,0 +1,346 @@
+package scanner_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"pg-bulk-flow/internal/model"
+	"pg-bulk-flow/internal/parser"
+	"pg-bulk-flow/internal/scanner"
+)
+
+func TestScannerRejects(t *testing.T) {
+	input := strings.Join([]string{
+		`{"count":10,"text":"Иванов","gender":"m"}`,
+		`not a json`,
+		`{"count":1,"text":"Пусто"}`,
+		`{"count":5,"text":"Петров","gender":"x"}`,
+		`{"count":0,"text":"Сидоров","gender":"m"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	rw := scanner.NewRejectWriter(&out)
+
+	sc := scanner.New(strings.NewReader(input), model.NameTypeSurname, new(parser.Parser))
+	sc.SetRejecter(rw)
+
+	var names []model.Name
+	for name := range sc.Scan(context.Background()) {
+		names = append(names, name)
+	}
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 1 || names[0].Text != "Иванов" {
+		t.Errorf("names = %v, want [Иванов]", names)
+	}
+
+	want := []struct {
+		line  int
+		stage scanner.Stage
+	}{
+		{2, scanner.StageJSON},
+		{3, scanner.StageEmptyFields},
+		{4, scanner.StageGender},
+		{5, scanner.StageCount},
+	}
+
+	dec := json.NewDecoder(&out)
+	for _, w := range want {
+		var r scanner.Reject
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode reject: %v", err)
+		}
+		if r.Line != w.line || r.Stage != w.stage || r.Error == "" {
+			t.Errorf("reject = %+v, want line %d stage %s", r, w.line, w.stage)
+		}
+	}
+	if dec.More() {
+		t.Error("unexpected extra rejects")
+	}
+}
+
+func TestScannerOversize(t *testing.T) {
+	long := `{"count":7,"text":"` + strings.Repeat("Я", 100) + `","gender":"f"}`
+	input := strings.Join([]string{
+		`{"count":10,"text":"Иванов","gender":"m"}`,
+		long,
+		`{"count":5,"text":"Петров","gender":"m"}`,
+		long,
+	}, "\r\n")
+
+	t.Run("skip", func(t *testing.T) {
+		var out bytes.Buffer
+		rw := scanner.NewRejectWriter(&out)
+
+		sc := scanner.New(strings.NewReader(input), model.NameTypeSurname, new(parser.Parser))
+		sc.SetMaxLine(64, true)
+		sc.SetRejecter(rw)
+
+		var names []string
+		for name := range sc.Scan(context.Background()) {
+			names = append(names, name.Text)
+		}
+		rw.Flush()
+
+		if err := sc.Err(); err != nil {
+			t.Fatalf("Err() = %v", err)
+		}
+		if got := strings.Join(names, ","); got != "Иванов,Петров" {
+			t.Errorf("names = %s, want Иванов,Петров", got)
+		}
+		if st := sc.Stats(); st.Total != 4 || st.Oversize != 2 {
+			t.Errorf("stats = %+v, want total 4, oversize 2", st)
+		}
+
+		var lines []int
+		dec := json.NewDecoder(&out)
+		for dec.More() {
+			var r scanner.Reject
+			if err := dec.Decode(&r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Stage != scanner.StageOversize {
+				t.Errorf("stage = %s, want %s", r.Stage, scanner.StageOversize)
+			}
+			lines = append(lines, r.Line)
+		}
+		if len(lines) != 2 || lines[0] != 2 || lines[1] != 4 {
+			t.Errorf("reject lines = %v, want [2 4]", lines)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		sc := scanner.New(strings.NewReader(input), model.NameTypeSurname, new(parser.Parser))
+		sc.SetMaxLine(64, false)
+
+		for range sc.Scan(context.Background()) {
+		}
+		if sc.Err() == nil {
+			t.Error("Err() = nil, want ErrScanFailed")
+		}
+	})
+}
+
+func TestScannerParallel(t *testing.T) {
+	var (
+		sb   strings.Builder
+		want []string
+	)
+	for i := range 5000 {
+		if i%7 == 0 {
+			sb.WriteString("garbage\n")
+			continue
+		}
+		text := "Фамилия" + letters(i)
+		fmt.Fprintf(&sb, `{"count":%d,"text":"%s","gender":"m"}`+"\n", i+1, text)
+		want = append(want, text)
+	}
+
+	for _, ordered := range []bool{true, false} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			parsers := []*parser.Parser{new(parser.Parser), new(parser.Parser), new(parser.Parser)}
+			sc := scanner.New(strings.NewReader(sb.String()), model.NameTypeSurname, nil)
+			sc.SetParseWorkers([]scanner.Parser{parsers[0], parsers[1], parsers[2]}, ordered)
+
+			var got []string
+			for name := range sc.Scan(context.Background()) {
+				got = append(got, name.Text)
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !ordered {
+				slices.Sort(got)
+				want := slices.Clone(want)
+				slices.Sort(want)
+				if !slices.Equal(got, want) {
+					t.Errorf("got %d names, want %d", len(got), len(want))
+				}
+			} else if !slices.Equal(got, want) {
+				t.Errorf("names out of order or missing: got %d, want %d", len(got), len(want))
+			}
+
+			var ps parser.Stats
+			for _, p := range parsers {
+				ps.Add(p.Stats())
+			}
+			st := sc.Stats()
+			if st.Total != 5000 || st.Unparsed != 5000-len(want) || ps.InvalidJSON != st.Unparsed {
+				t.Errorf("scanner stats = %+v, parser stats = %+v", st, ps)
+			}
+		})
+	}
+}
+
+// letters записывает n буквами, чтобы имена были уникальными и проходили валидацию.
+func letters(n int) string {
+	var b []rune
+	for {
+		b = append(b, 'а'+rune(n%26))
+		if n /= 26; n == 0 {
+			return string(b)
+		}
+	}
+}
+
+func TestScannerParallelBreak(t *testing.T) {
+	// каждая вторая строка некорректна: воркеры пишут статистику парсеров
+	input := strings.Repeat(`{"count":10,"text":"Иванов","gender":"m"}`+"\n{\n", 10000)
+	parsers := []*parser.Parser{new(parser.Parser), new(parser.Parser)}
+	sc := scanner.New(strings.NewReader(input), model.NameTypeSurname, nil)
+	sc.SetParseWorkers([]scanner.Parser{parsers[0], parsers[1]}, true)
+
+	n := 0
+	for range sc.Scan(context.Background()) {
+		if n++; n == 100 {
+			break
+		}
+	}
+	if n != 100 {
+		t.Errorf("n = %d, want 100", n)
+	}
+
+	// после выхода из цикла горутины сканера уже остановлены: под -race чтение
+	// статистики не должно гоняться с воркерами и читателем
+	var ps parser.Stats
+	for _, p := range parsers {
+		ps.Add(p.Stats())
+	}
+	if st := sc.Stats(); st.Total-st.Unparsed != 100 || ps.InvalidJSON < st.Unparsed {
+		t.Errorf("scanner stats = %+v, parser stats = %+v", st, ps)
+	}
+}
+
+func TestScannerInferGender(t *testing.T) {
+	input := strings.Join([]string{
+		`{"count":10,"text":"Иванова","fname":"x"}`,
+		`{"count":10,"text":"Петров","gender":"f"}`,
+		`{"count":10,"text":"Шевчук","fname":"x"}`,
+		`{"count":10,"text":"Сидоров","fname":"x"}`,
+	}, "\n")
+
+	sc := scanner.New(strings.NewReader(input), model.NameTypeSurname, new(parser.Parser))
+	sc.SetInferGender(true)
+
+	var got []model.Gender
+	for name := range sc.Scan(context.Background()) {
+		got = append(got, name.Gender)
+	}
+
+	want := []model.Gender{model.GenderFemale, model.GenderFemale, model.GenderUnknown, model.GenderMale}
+	if !slices.Equal(got, want) {
+		t.Errorf("genders = %v, want %v", got, want)
+	}
+	if n := sc.Stats().Inferred; n != 2 {
+		t.Errorf("Inferred = %d, want 2", n)
+	}
+}
+
+func TestScannerSkipLines(t *testing.T) {
+	var sb strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&sb, `{"count":%d,"text":"Фамилия%s","gender":"m"}`+"\n", i+1, letters(i))
+	}
+
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			sc := scanner.New(strings.NewReader(sb.String()), model.NameTypeSurname, new(parser.Parser))
+			if workers > 1 {
+				sc.SetParseWorkers([]scanner.Parser{new(parser.Parser), new(parser.Parser), new(parser.Parser)}, true)
+			}
+			sc.SetSkipLines(2500)
+
+			var lines []int
+			for name := range sc.Scan(context.Background()) {
+				lines = append(lines, name.Line)
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(lines) != 500 || lines[0] != 2501 || lines[len(lines)-1] != 3000 {
+				t.Errorf("got %d lines %v..., want 2501..3000", len(lines), lines[:min(3, len(lines))])
+			}
+			if st := sc.Stats(); st.Skipped != 2500 || st.Total != 500 {
+				t.Errorf("stats = %+v, want skipped 2500, total 500", st)
+			}
+		})
+	}
+}
+
+func TestScannerCanceled(t *testing.T) {
+	var sb strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&sb, `{"count":%d,"text":"Фамилия%s","gender":"m"}`+"\n", i+1, letters(i))
+	}
+
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			sc := scanner.New(strings.NewReader(sb.String()), model.NameTypeSurname, new(parser.Parser))
+			if workers > 1 {
+				sc.SetParseWorkers([]scanner.Parser{new(parser.Parser), new(parser.Parser), new(parser.Parser)}, true)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var n int
+			for name := range sc.Scan(ctx) {
+				n++
+				if name.Line != n {
+					t.Fatalf("line %d at position %d", name.Line, n)
+				}
+				if n == 10 {
+					cancel()
+				}
+			}
+			if err := sc.Err(); err != nil {
+				t.Errorf("Err() = %v, want nil after cancel", err)
+			}
+			if n < 10 || n == 3000 {
+				t.Errorf("got %d records, want scan to stop early", n)
+			}
+		})
+	}
+}
+
+// TestScannerBundledData проверяет, что нормализация не сводит разные записи
+// data/names к одному ключу уникального индекса (name_text, name_type, gender):
+// иначе загрузка по умолчанию (-on-conflict error) падает на дубликате.
+func TestScannerBundledData(t *testing.T) {
+	f, err := os.Open("../../data/names/names.jsonl")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+
+	type key struct {
+		text   string
+		gender model.Gender
+	}
+	seen := make(map[key]int)
+
+	sc := scanner.New(f, model.NameTypeName, new(parser.Parser))
+	for v := range sc.Scan(context.Background()) {
+		k := key{v.Text, v.Gender}
+		if line, ok := seen[k]; ok {
+			t.Errorf("lines %d and %d: duplicate key %q %s", line, v.Line, v.Text, v.Gender)
+		}
+		seen[k] = v.Line
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) == 0 {
+		t.Error("no records loaded")
+	}
+}
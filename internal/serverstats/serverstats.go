@@ -0,0 +1,151 @@
+package serverstats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Database счетчики pg_stat_database для текущей базы.
+type Database struct {
+	XactCommit   int64 `json:"xact_commit"`
+	XactRollback int64 `json:"xact_rollback"`
+	BlksRead     int64 `json:"blks_read"`
+	BlksHit      int64 `json:"blks_hit"`
+	TupInserted  int64 `json:"tup_inserted"`
+	TupUpdated   int64 `json:"tup_updated"`
+	TupDeleted   int64 `json:"tup_deleted"`
+	TempBytes    int64 `json:"temp_bytes"`
+}
+
+// IO счетчики pg_stat_io (PostgreSQL 16+), просуммированные по всем строкам, в блоках.
+// Представление общее для кластера, поэтому сюда попадает и активность других сессий.
+type IO struct {
+	Reads     int64 `json:"reads"`
+	Writes    int64 `json:"writes"`
+	Extends   int64 `json:"extends"`
+	Hits      int64 `json:"hits"`
+	Evictions int64 `json:"evictions"`
+	Fsyncs    int64 `json:"fsyncs"`
+}
+
+// Snapshot состояние сервера в момент опроса.
+type Snapshot struct {
+	WALLSN    uint64 // pg_current_wal_lsn()
+	TableSize int64  // pg_total_relation_size('names')
+	Database  Database
+	IO        *IO // nil, если сервер не поддерживает pg_stat_io
+}
+
+// Take опрашивает сервер через conn.
+//
+// Статистика сессий попадает в pg_stat_* не сразу, а при сбросе накопленных
+// счетчиков. Для conn сброс вызывается принудительно (pg_stat_force_next_flush,
+// PostgreSQL 15+, по умолчанию только для суперпользователя); счетчики других
+// соединений, например воркеров parallelcopy, могут отставать примерно на секунду.
+func Take(ctx context.Context, conn *pgx.Conn) (Snapshot, error) {
+	// ошибка не важна: без сброса счетчики только чуть отстанут
+	conn.Exec(ctx, `SELECT pg_stat_force_next_flush()`)
+
+	var (
+		s   Snapshot
+		lsn string
+	)
+	err := conn.QueryRow(ctx, `SELECT pg_current_wal_lsn()::text, pg_total_relation_size('names'),
+		xact_commit, xact_rollback, blks_read, blks_hit, tup_inserted, tup_updated, tup_deleted, temp_bytes
+		FROM pg_stat_database WHERE datname = current_database()`).Scan(
+		&lsn, &s.TableSize,
+		&s.Database.XactCommit, &s.Database.XactRollback, &s.Database.BlksRead, &s.Database.BlksHit,
+		&s.Database.TupInserted, &s.Database.TupUpdated, &s.Database.TupDeleted, &s.Database.TempBytes)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if s.WALLSN, err = ParseLSN(lsn); err != nil {
+		return Snapshot{}, err
+	}
+
+	var hasIO bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('pg_catalog.pg_stat_io') IS NOT NULL`).Scan(&hasIO); err != nil {
+		return Snapshot{}, err
+	}
+	if hasIO {
+		var io IO
+		err := conn.QueryRow(ctx, `SELECT coalesce(sum(reads), 0)::bigint, coalesce(sum(writes), 0)::bigint,
+			coalesce(sum(extends), 0)::bigint, coalesce(sum(hits), 0)::bigint,
+			coalesce(sum(evictions), 0)::bigint, coalesce(sum(fsyncs), 0)::bigint
+			FROM pg_stat_io`).Scan(&io.Reads, &io.Writes, &io.Extends, &io.Hits, &io.Evictions, &io.Fsyncs)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		s.IO = &io
+	}
+
+	return s, nil
+}
+
+// ParseLSN разбирает текстовое представление pg_lsn вида "16/B374D848".
+func ParseLSN(s string) (uint64, error) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", s, err)
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", s, err)
+	}
+	return h<<32 | l, nil
+}
+
+// Delta разница между двумя опросами для отчета.
+type Delta struct {
+	WALBytes    int64    `json:"wal_bytes"`
+	WALPerRow   float64  `json:"wal_bytes_per_row,omitempty"`
+	TableBytes  int64    `json:"table_size_delta"` // -truncate выполняется до первого опроса
+	TableSize   int64    `json:"table_size"`       // размер после загрузки
+	BytesPerRow float64  `json:"table_bytes_per_row,omitempty"`
+	Database    Database `json:"database"`
+	IO          *IO      `json:"io,omitempty"`
+}
+
+// Diff считает разницу after - before. rows — количество записанных в таблицу строк
+// (вставленных или обновленных), по нему считаются удельные величины (при rows == 0
+// они не заполняются).
+func Diff(before, after Snapshot, rows int64) Delta {
+	d := Delta{
+		WALBytes:   int64(after.WALLSN - before.WALLSN),
+		TableBytes: after.TableSize - before.TableSize,
+		TableSize:  after.TableSize,
+		Database: Database{
+			XactCommit:   after.Database.XactCommit - before.Database.XactCommit,
+			XactRollback: after.Database.XactRollback - before.Database.XactRollback,
+			BlksRead:     after.Database.BlksRead - before.Database.BlksRead,
+			BlksHit:      after.Database.BlksHit - before.Database.BlksHit,
+			TupInserted:  after.Database.TupInserted - before.Database.TupInserted,
+			TupUpdated:   after.Database.TupUpdated - before.Database.TupUpdated,
+			TupDeleted:   after.Database.TupDeleted - before.Database.TupDeleted,
+			TempBytes:    after.Database.TempBytes - before.Database.TempBytes,
+		},
+	}
+	if before.IO != nil && after.IO != nil {
+		d.IO = &IO{
+			Reads:     after.IO.Reads - before.IO.Reads,
+			Writes:    after.IO.Writes - before.IO.Writes,
+			Extends:   after.IO.Extends - before.IO.Extends,
+			Hits:      after.IO.Hits - before.IO.Hits,
+			Evictions: after.IO.Evictions - before.IO.Evictions,
+			Fsyncs:    after.IO.Fsyncs - before.IO.Fsyncs,
+		}
+	}
+	if rows > 0 {
+		d.WALPerRow = float64(d.WALBytes) / float64(rows)
+		d.BytesPerRow = float64(d.TableBytes) / float64(rows)
+	}
+	return d
+}
@@ -0,0 +1,64 @@
+package serverstats
+
+import "testing"
+
+func TestParseLSN(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{"0/0", 0, false},
+		{"0/16B3748", 0x16B3748, false},
+		{"16/B374D848", 0x16_B374D848, false},
+		{"FFFFFFFF/FFFFFFFF", 1<<64 - 1, false},
+		{"", 0, true},
+		{"16B374D848", 0, true},
+		{"1/G", 0, true},
+		{"100000000/0", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLSN(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLSN(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLSN(%q) = %#x, want %#x", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := Snapshot{
+		WALLSN:    0x1_FFFFF000,
+		TableSize: 8192,
+		Database:  Database{XactCommit: 10, TupInserted: 5, BlksHit: 100},
+	}
+	after := Snapshot{
+		WALLSN:    0x2_00001000,
+		TableSize: 8192 + 40960,
+		Database:  Database{XactCommit: 14, TupInserted: 1005, BlksHit: 400},
+		IO:        &IO{Writes: 7},
+	}
+
+	d := Diff(before, after, 1000)
+	if d.WALBytes != 0x2000 || d.WALPerRow != 8.192 {
+		t.Errorf("WAL = %d (%v per row), want 8192 (8.192)", d.WALBytes, d.WALPerRow)
+	}
+	if d.TableBytes != 40960 || d.TableSize != 49152 || d.BytesPerRow != 40.96 {
+		t.Errorf("table = %+v", d)
+	}
+	if want := (Database{XactCommit: 4, TupInserted: 1000, BlksHit: 300}); d.Database != want {
+		t.Errorf("Database = %+v, want %+v", d.Database, want)
+	}
+	if d.IO != nil {
+		t.Errorf("IO = %+v, want nil without a before sample", d.IO)
+	}
+
+	if d := Diff(before, after, 0); d.WALPerRow != 0 || d.BytesPerRow != 0 {
+		t.Errorf("per-row values without rows = %v, %v", d.WALPerRow, d.BytesPerRow)
+	}
+}
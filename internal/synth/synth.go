@@ -0,0 +1,325 @@
+package synth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"strconv"
+	"unicode/utf8"
+
+	"pg-bulk-flow/internal/model"
+)
+
+// Config параметры генерируемых данных. Доли задаются числами от 0 до 1.
+type Config struct {
+	Seed       uint64
+	MinLen     int     // минимальная длина имени в буквах
+	MaxLen     int     // максимальная длина имени в буквах
+	MeanLen    float64 // длина распределена нормально с этим средним...
+	StdDevLen  float64 // ...и отклонением, обрезается по MinLen и MaxLen
+	Latin      float64 // доля имен латиницей, остальные кириллицей
+	Female     float64 // доля женских записей
+	Unknown    float64 // доля записей с неизвестным полом
+	Ethnic     float64 // доля записей с полем ethnic
+	NumberLong float64 // доля count в формате {"$numberLong": "..."}
+	Malformed  float64 // доля намеренно испорченных строк (только для JSONL)
+}
+
+var DefaultConfig = Config{
+	Seed:       1,
+	MinLen:     3,
+	MaxLen:     16,
+	MeanLen:    8,
+	StdDevLen:  2,
+	Latin:      0.1,
+	Female:     0.45,
+	Unknown:    0.05,
+	Ethnic:     0.7,
+	NumberLong: 0.01,
+}
+
+// Validate проверяет параметры.
+func (c Config) Validate() error {
+	if c.MinLen < 1 || c.MaxLen < c.MinLen {
+		return fmt.Errorf("invalid name length range %d..%d", c.MinLen, c.MaxLen)
+	}
+	if c.StdDevLen < 0 {
+		return errors.New("negative name length deviation")
+	}
+	for _, v := range []float64{c.Latin, c.Female, c.Unknown, c.Ethnic, c.NumberLong, c.Malformed} {
+		if v < 0 || v > 1 || math.IsNaN(v) {
+			return fmt.Errorf("share %v out of range [0, 1]", v)
+		}
+	}
+	if c.Female+c.Unknown > 1 {
+		return errors.New("female and unknown gender shares exceed 1")
+	}
+	return nil
+}
+
+// alphabet буквы письменности. Имя чередует согласные и гласные, начиная с согласной.
+type alphabet struct {
+	consonants []rune
+	vowels     []rune
+	female     rune // окончание женской формы
+	ethnic     []string
+}
+
+var (
+	cyrillic = alphabet{
+		consonants: []rune("бвгджзклмнпрстфхчш"),
+		vowels:     []rune("аеиоуя"),
+		female:     'а',
+		ethnic:     []string{"slav", "tur", "jew", "arm", "geo"},
+	}
+	latin = alphabet{
+		consonants: []rune("bcdfghklmnprstvz"),
+		vowels:     []rune("aeiou"),
+		female:     'a',
+		ethnic:     []string{"lat", "ger", "eng"},
+	}
+)
+
+// letters возвращает буквы для позиции pos в имени.
+func (a *alphabet) letters(pos int) []rune {
+	if pos%2 == 0 {
+		return a.consonants
+	}
+	return a.vowels
+}
+
+// code однозначно кодирует номер записи начальными буквами имени, поэтому тексты не
+// повторяются (names уникальна по тексту, типу и полу). Номера перемешиваются
+// биекцией i -> (i*mul + add) mod size, чтобы соседние записи не были похожи.
+type code struct {
+	alpha *alphabet
+	len   int    // букв в коде
+	size  uint64 // количество различных кодов
+	add   uint64
+	next  uint64
+}
+
+// mul взаимно просто с size: его делители — только множители размеров алфавитов (2, 3, 5).
+const mul = 2654435761
+
+func newCode(a *alphabet, n int64, add uint64) code {
+	c := code{alpha: a, size: 1}
+	for c.size < uint64(max(n, 1)) {
+		c.size *= uint64(len(a.letters(c.len)))
+		c.len++
+	}
+	c.add = add % c.size
+	return c
+}
+
+// appendNext дописывает код следующего номера. После size номеров коды повторяются.
+func (c *code) appendNext(dst []byte) []byte {
+	hi, lo := bits.Mul64(c.next%c.size, mul)
+	v := (bits.Rem64(hi, lo, c.size) + c.add) % c.size
+	c.next++
+
+	for pos := range c.len {
+		letters := c.alpha.letters(pos)
+		dst = utf8.AppendRune(dst, letters[v%uint64(len(letters))])
+		v /= uint64(len(letters))
+	}
+	return dst
+}
+
+// Generator порождает случайные, но воспроизводимые по Seed записи. Не потокобезопасен.
+type Generator struct {
+	cfg   Config
+	rng   *rand.Rand
+	cyr   code
+	lat   code
+	text  []byte
+	lines uint64
+}
+
+// New создает генератор. n — сколько записей планируется получить: по нему выбирается
+// длина уникального кода, а значит и минимальная длина имени. Если получить больше,
+// тексты начнут повторяться.
+func New(cfg Config, n int64) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewPCG(cfg.Seed, 0x9e3779b97f4a7c15))
+	return &Generator{
+		cfg: cfg,
+		rng: rng,
+		cyr: newCode(&cyrillic, n, rng.Uint64()),
+		lat: newCode(&latin, n, rng.Uint64()),
+	}, nil
+}
+
+// record запись до кодирования.
+type record struct {
+	count  int64
+	text   []byte // действителен до следующего вызова next
+	gender model.Gender
+	ethnic string
+}
+
+func (g *Generator) next() record {
+	var r record
+
+	a, c := &cyrillic, &g.cyr
+	if g.rng.Float64() < g.cfg.Latin {
+		a, c = &latin, &g.lat
+	}
+
+	switch p := g.rng.Float64(); {
+	case p < g.cfg.Female:
+		r.gender = model.GenderFemale
+	case p < g.cfg.Female+g.cfg.Unknown:
+		r.gender = model.GenderUnknown
+	default:
+		r.gender = model.GenderMale
+	}
+
+	length := g.cfg.MeanLen + g.rng.NormFloat64()*g.cfg.StdDevLen
+	length = min(max(math.Round(length), float64(g.cfg.MinLen)), float64(g.cfg.MaxLen))
+	n := int(length)
+
+	female := r.gender == model.GenderFemale
+	if female && n%2 == 1 {
+		// окончание — гласная после согласной, то есть на нечетной позиции: длина
+		// женского имени четная, если это позволяют границы
+		switch {
+		case n+1 <= g.cfg.MaxLen:
+			n++
+		case n-1 >= g.cfg.MinLen:
+			n--
+		}
+	}
+
+	// код, затем случайный хвост до нужной длины и окончание женской формы
+	g.text = c.appendNext(g.text[:0])
+	pos := c.len
+	tail := n - pos
+	if female {
+		tail--
+	}
+	for ; tail > 0; tail-- {
+		letters := a.letters(pos)
+		g.text = utf8.AppendRune(g.text, letters[g.rng.IntN(len(letters))])
+		pos++
+	}
+	if female {
+		// окончание ставится после согласной, если для нее осталось место
+		if pos%2 == 0 && pos+2 <= g.cfg.MaxLen {
+			g.text = utf8.AppendRune(g.text, a.consonants[g.rng.IntN(len(a.consonants))])
+		}
+		g.text = utf8.AppendRune(g.text, a.female)
+	}
+	g.text = capitalize(g.text)
+	r.text = g.text
+
+	// частоты имен распределены с длинным хвостом: большинство редкие
+	r.count = 1 + int64(math.Min(math.Exp(math.Abs(g.rng.NormFloat64())*3), 1e6))
+
+	if g.rng.Float64() < g.cfg.Ethnic {
+		r.ethnic = a.ethnic[g.rng.IntN(len(a.ethnic))]
+	}
+	return r
+}
+
+// capitalize делает первую букву заглавной, как в исходных данных. Для букв
+// алфавитов генератора заглавная занимает столько же байт, что и строчная.
+func capitalize(text []byte) []byte {
+	r, size := utf8.DecodeRune(text)
+	switch {
+	case 'a' <= r && r <= 'z':
+		r += 'A' - 'a'
+	case 'а' <= r && r <= 'я':
+		r += 'А' - 'а'
+	}
+	utf8.EncodeRune(text[:size], r)
+	return text
+}
+
+// Name возвращает следующую корректную запись в виде, в котором ее выдал бы сканер.
+// Malformed и NumberLong здесь не применяются.
+func (g *Generator) Name(nameType model.NameType) model.Name {
+	r := g.next()
+	v := model.Name{
+		Count:  int32(r.count),
+		Type:   nameType,
+		Text:   string(r.text),
+		Gender: r.gender,
+	}
+	if r.ethnic != "" {
+		v.Ethnic = []string{r.ethnic}
+	}
+	return v
+}
+
+// AppendLine дописывает к dst следующую строку JSONL с переводом строки в формате,
+// который принимает parser.Parser. Доля Malformed строк будет отклонена при загрузке.
+func (g *Generator) AppendLine(dst []byte) []byte {
+	g.lines++
+	r := g.next()
+
+	malformed := g.rng.Float64() < g.cfg.Malformed
+	kind := -1
+	if malformed {
+		kind = g.rng.IntN(4)
+	}
+
+	start := len(dst)
+	dst = append(dst, `{"_id":{"$oid":"4bdaa14b`...)
+	dst = appendHex(dst, g.lines)
+	dst = append(dst, `"},"count":`...)
+	switch {
+	case kind == 0:
+		dst = append(dst, `"many"`...) // не число
+	case g.rng.Float64() < g.cfg.NumberLong:
+		dst = append(dst, `{"$numberLong":"`...)
+		dst = strconv.AppendInt(dst, r.count, 10)
+		dst = append(dst, `"}`...)
+	default:
+		dst = strconv.AppendInt(dst, r.count, 10)
+	}
+
+	dst = append(dst, `,"text":"`...)
+	dst = append(dst, r.text...)
+	if kind == 1 {
+		dst = append(dst, "ъz"...) // кириллица и латиница в одном слове
+	}
+
+	dst = append(dst, `","gender":"`...)
+	switch {
+	case kind == 2:
+		dst = append(dst, 'x')
+	case r.gender == model.GenderMale:
+		dst = append(dst, 'm')
+	case r.gender == model.GenderFemale:
+		dst = append(dst, 'f')
+	default:
+		dst = append(dst, 'u')
+	}
+	dst = append(dst, '"')
+
+	if r.ethnic != "" {
+		dst = append(dst, `,"ethnic":["`...)
+		dst = append(dst, r.ethnic...)
+		dst = append(dst, `"]`...)
+	}
+	dst = append(dst, '}')
+
+	if kind == 3 {
+		dst = dst[:start+(len(dst)-start)/2] // обрезанный JSON
+	}
+	return append(dst, '\n')
+}
+
+// appendHex дописывает v как 16 шестнадцатеричных цифр.
+func appendHex(dst []byte, v uint64) []byte {
+	const digits = "0123456789abcdef"
+	for shift := 60; shift >= 0; shift -= 4 {
+		dst = append(dst, digits[v>>shift&0xf])
+	}
+	return dst
+}
@@ -0,0 +1,182 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"unicode/utf8"
+
+	"pg-bulk-flow/internal/model"
+	"pg-bulk-flow/internal/parser"
+)
+
+func TestNamesUnique(t *testing.T) {
+	const n = 200000
+	cfg := DefaultConfig
+	cfg.MinLen = 1
+	g, err := New(cfg, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool, n)
+	var female, latin int
+	for range n {
+		v := g.Name(model.NameTypeSurname)
+		if seen[v.Text] {
+			t.Fatalf("duplicate text %q", v.Text)
+		}
+		seen[v.Text] = true
+
+		norm, err := model.NormalizeName(v.Text)
+		if err != nil || norm != v.Text {
+			t.Fatalf("NormalizeName(%q) = %q, %v", v.Text, norm, err)
+		}
+		if err := model.ValidateName(v.Text); err != nil {
+			t.Fatalf("ValidateName(%q) = %v", v.Text, err)
+		}
+		if l := utf8.RuneCountInString(v.Text); l > cfg.MaxLen {
+			t.Fatalf("%q longer than %d", v.Text, cfg.MaxLen)
+		}
+		if v.Gender == model.GenderFemale {
+			female++
+		}
+		if v.Text[0] < utf8.RuneSelf {
+			latin++
+		}
+	}
+
+	for _, s := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"female", float64(female) / n, cfg.Female},
+		{"latin", float64(latin) / n, cfg.Latin},
+	} {
+		if math.Abs(s.got-s.want) > 0.01 {
+			t.Errorf("%s share = %.3f, want %.3f", s.name, s.got, s.want)
+		}
+	}
+}
+
+func TestNameLength(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		minLen, maxLen int
+		mean           float64
+	}{
+		{"OddMax", 3, 7, 7},
+		{"EvenMax", 3, 8, 8},
+		{"OddFixed", 7, 7, 7},
+		{"OddMin", 5, 9, 5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			const n = 1000 // код из 3 букв, короче любой MinLen ниже
+			cfg := DefaultConfig
+			cfg.MinLen, cfg.MaxLen, cfg.MeanLen = tt.minLen, tt.maxLen, tt.mean
+			cfg.Female = 1
+			cfg.Unknown = 0
+			g, err := New(cfg, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range n {
+				v := g.Name(model.NameTypeName)
+				if l := utf8.RuneCountInString(v.Text); l < cfg.MinLen || l > cfg.MaxLen {
+					t.Fatalf("%q length %d out of %d..%d", v.Text, l, cfg.MinLen, cfg.MaxLen)
+				}
+			}
+		})
+	}
+}
+
+func TestLinesParse(t *testing.T) {
+	const n = 20000
+	cfg := DefaultConfig
+	cfg.Malformed = 0.1
+	cfg.NumberLong = 0.2
+
+	g, err := New(cfg, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		p       parser.Parser
+		buf     []byte
+		failed  int
+		numLong int
+	)
+	for range n {
+		buf = g.AppendLine(buf[:0])
+		if buf[len(buf)-1] != '\n' || bytes.Count(buf, []byte{'\n'}) != 1 {
+			t.Fatalf("line %q must end with a single newline", buf)
+		}
+		if bytes.Contains(buf, []byte("$numberLong")) {
+			numLong++
+		}
+		v, err := p.Parse(context.Background(), buf[:len(buf)-1])
+		if err == nil {
+			err = model.ValidateName(v.Text)
+		}
+		if err != nil {
+			failed++
+		}
+	}
+
+	if share := float64(failed) / n; math.Abs(share-cfg.Malformed) > 0.01 {
+		t.Errorf("rejected share = %.3f, want %.3f", share, cfg.Malformed)
+	}
+	if share := float64(numLong) / n; math.Abs(share-cfg.NumberLong*(1-cfg.Malformed/4)) > 0.015 {
+		t.Errorf("$numberLong share = %.3f, want about %.3f", share, cfg.NumberLong)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	gen := func(seed uint64) []byte {
+		cfg := DefaultConfig
+		cfg.Seed = seed
+		g, err := New(cfg, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf []byte
+		for range 1000 {
+			buf = g.AppendLine(buf)
+		}
+		return buf
+	}
+
+	if !bytes.Equal(gen(1), gen(1)) {
+		t.Error("same seed produced different output")
+	}
+	if bytes.Equal(gen(1), gen(2)) {
+		t.Error("different seeds produced the same output")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"LenRange", func(c *Config) { c.MinLen, c.MaxLen = 5, 4 }},
+		{"ZeroLen", func(c *Config) { c.MinLen = 0 }},
+		{"NegativeDeviation", func(c *Config) { c.StdDevLen = -1 }},
+		{"Share", func(c *Config) { c.Latin = 1.5 }},
+		{"Gender", func(c *Config) { c.Female, c.Unknown = 0.6, 0.5 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig
+			tt.modify(&cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+	if err := DefaultConfig.Validate(); err != nil {
+		t.Errorf("DefaultConfig: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Дубликаты, загруженные до появления индекса, схлопываются в строку с меньшим id:
+-- счетчики складываются (как в -on-conflict=sum), остальные строки удаляются.
+UPDATE names n
+SET count = d.total
+FROM (
+    SELECT min(id) AS id, least(sum(count), 2147483647)::int AS total
+    FROM names
+    GROUP BY name_text, name_type, gender
+    HAVING count(*) > 1
+) d
+WHERE n.id = d.id;
+
+DELETE FROM names n
+USING names k
+WHERE n.name_text = k.name_text
+  AND n.name_type = k.name_type
+  AND n.gender = k.gender
+  AND n.id > k.id;
+
+CREATE UNIQUE INDEX names_text_type_gender_key ON names (name_text, name_type, gender);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX names_text_type_gender_key;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE names
+    ADD COLUMN fname TEXT NOT NULL DEFAULT '',
+    ADD COLUMN f_form TEXT NOT NULL DEFAULT '',
+    ADD COLUMN m_form TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ethnic TEXT[];
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE names
+    DROP COLUMN fname,
+    DROP COLUMN f_form,
+    DROP COLUMN m_form,
+    DROP COLUMN ethnic;
+-- +goose StatementEnd